@@ -0,0 +1,140 @@
+// Copyright (c) 2024 Red Hat, Inc.
+
+package v1alpha1
+
+import (
+	"encoding/json"
+	"github.com/opendatahub-io/odh-nim-operator/api/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"reflect"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+	"strconv"
+)
+
+const (
+	// annotationObservedGeneration is used for preserving the v1beta1 observed generation, v1alpha1 has no matching
+	// field
+	annotationObservedGeneration = "nim.opendatahub.io/v1beta1-observed-generation"
+	// annotationV1beta1Fields is used for preserving the v1beta1 fields added after v1alpha1 was frozen, encoded as
+	// JSON, v1alpha1 has no matching fields
+	annotationV1beta1Fields = "nim.opendatahub.io/v1beta1-fields"
+)
+
+// v1beta1Fields is used for encapsulating the v1beta1 fields preserved in the v1beta1-fields annotation
+type v1beta1Fields struct {
+	Propagation                v1beta1.OdhNimAppSpecPropagation     `json:"propagation,omitempty"`
+	Storage                    v1beta1.OdhNimAppSpecStorage         `json:"storage,omitempty"`
+	Warmup                     v1beta1.OdhNimAppSpecWarmup          `json:"warmup,omitempty"`
+	Offline                    v1beta1.OdhNimAppSpecOffline         `json:"offline,omitempty"`
+	Mirrors                    map[string]string                    `json:"mirrors,omitempty"`
+	ApiKeyHash                 string                               `json:"apiKeyHash,omitempty"`
+	Warmups                    []v1beta1.OdhNimAppStatusWarmup      `json:"warmups,omitempty"`
+	LastCatalogDiff            *v1beta1.OdhNimAppStatusCatalogDiff  `json:"lastCatalogDiff,omitempty"`
+	ConsecutiveContentFailures int32                                `json:"consecutiveContentFailures,omitempty"`
+	ContentRetryTime           *metav1.Time                         `json:"contentRetryTime,omitempty"`
+	ImageMirrors               []v1beta1.OdhNimAppStatusImageMirror `json:"imageMirrors,omitempty"`
+}
+
+// ConvertTo is used for converting this v1alpha1 OdhNimApp to the v1beta1 hub version, the template and configmap
+// references move from the spec to the status
+func (src *OdhNimApp) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1beta1.OdhNimApp)
+
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+	if observedGeneration, found := dst.Annotations[annotationObservedGeneration]; found {
+		dst.Status.ObservedGeneration, _ = strconv.ParseInt(observedGeneration, 10, 64)
+		delete(dst.Annotations, annotationObservedGeneration)
+	}
+	fields := v1beta1Fields{}
+	if encoded, found := dst.Annotations[annotationV1beta1Fields]; found {
+		if err := json.Unmarshal([]byte(encoded), &fields); err != nil {
+			return err
+		}
+		delete(dst.Annotations, annotationV1beta1Fields)
+	}
+	if len(dst.Annotations) == 0 {
+		dst.Annotations = nil
+	}
+
+	dst.Spec.ApiKey.Validate = src.Spec.ApiKey.Validate
+	dst.Spec.ApiKey.SecretRef = src.Spec.ApiKey.SecretRef.DeepCopy()
+	dst.Spec.Content.Update = src.Spec.Content.Update
+	dst.Spec.TeardownPolicy = v1beta1.TeardownPolicy(src.Spec.TeardownPolicy)
+	dst.Spec.Schedule.Interval = src.Spec.Schedule.Interval.DeepCopy()
+	dst.Spec.Schedule.Cron = src.Spec.Schedule.Cron
+	dst.Spec.Propagation = fields.Propagation
+	dst.Spec.Storage = fields.Storage
+	dst.Spec.Warmup = fields.Warmup
+	dst.Spec.Offline = fields.Offline
+	dst.Spec.Mirrors = fields.Mirrors
+
+	dst.Status.Conditions = append([]metav1.Condition(nil), src.Status.Conditions...)
+	dst.Status.TemplateRef = src.Spec.TemplateRef.DeepCopy()
+	dst.Status.ConfigMapRef = src.Spec.Content.ConfigMapRef.DeepCopy()
+	dst.Status.LastValidationTime = src.Status.LastValidationTime.DeepCopy()
+	dst.Status.LastContentUpdateTime = src.Status.LastContentUpdateTime.DeepCopy()
+	dst.Status.ObservedValidateRequestedAt = src.Status.ObservedValidateRequestedAt
+	dst.Status.ObservedRefreshRequestedAt = src.Status.ObservedRefreshRequestedAt
+	dst.Status.ApiKeyHash = fields.ApiKeyHash
+	dst.Status.Warmups = fields.Warmups
+	dst.Status.LastCatalogDiff = fields.LastCatalogDiff
+	dst.Status.ConsecutiveContentFailures = fields.ConsecutiveContentFailures
+	dst.Status.ContentRetryTime = fields.ContentRetryTime
+	dst.Status.ImageMirrors = fields.ImageMirrors
+
+	return nil
+}
+
+// ConvertFrom is used for converting the v1beta1 hub version to this v1alpha1 OdhNimApp, the template and configmap
+// references move from the status to the spec
+func (dst *OdhNimApp) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1beta1.OdhNimApp)
+
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+	if src.Status.ObservedGeneration != 0 {
+		if dst.Annotations == nil {
+			dst.Annotations = map[string]string{}
+		}
+		dst.Annotations[annotationObservedGeneration] = strconv.FormatInt(src.Status.ObservedGeneration, 10)
+	}
+	fields := v1beta1Fields{
+		Propagation:                *src.Spec.Propagation.DeepCopy(),
+		Storage:                    *src.Spec.Storage.DeepCopy(),
+		Warmup:                     *src.Spec.Warmup.DeepCopy(),
+		Offline:                    *src.Spec.Offline.DeepCopy(),
+		Mirrors:                    src.Spec.Mirrors,
+		ApiKeyHash:                 src.Status.ApiKeyHash,
+		Warmups:                    src.Status.Warmups,
+		LastCatalogDiff:            src.Status.LastCatalogDiff,
+		ConsecutiveContentFailures: src.Status.ConsecutiveContentFailures,
+		ContentRetryTime:           src.Status.ContentRetryTime,
+		ImageMirrors:               src.Status.ImageMirrors,
+	}
+	if !reflect.DeepEqual(fields, v1beta1Fields{}) {
+		encoded, err := json.Marshal(fields)
+		if err != nil {
+			return err
+		}
+		if dst.Annotations == nil {
+			dst.Annotations = map[string]string{}
+		}
+		dst.Annotations[annotationV1beta1Fields] = string(encoded)
+	}
+
+	dst.Spec.ApiKey.Validate = src.Spec.ApiKey.Validate
+	dst.Spec.ApiKey.SecretRef = src.Spec.ApiKey.SecretRef.DeepCopy()
+	dst.Spec.Content.Update = src.Spec.Content.Update
+	dst.Spec.Content.ConfigMapRef = src.Status.ConfigMapRef.DeepCopy()
+	dst.Spec.TemplateRef = src.Status.TemplateRef.DeepCopy()
+	dst.Spec.TeardownPolicy = TeardownPolicy(src.Spec.TeardownPolicy)
+	dst.Spec.Schedule.Interval = src.Spec.Schedule.Interval.DeepCopy()
+	dst.Spec.Schedule.Cron = src.Spec.Schedule.Cron
+
+	dst.Status.Conditions = append([]metav1.Condition(nil), src.Status.Conditions...)
+	dst.Status.LastValidationTime = src.Status.LastValidationTime.DeepCopy()
+	dst.Status.LastContentUpdateTime = src.Status.LastContentUpdateTime.DeepCopy()
+	dst.Status.ObservedValidateRequestedAt = src.Status.ObservedValidateRequestedAt
+	dst.Status.ObservedRefreshRequestedAt = src.Status.ObservedRefreshRequestedAt
+
+	return nil
+}
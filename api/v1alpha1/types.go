@@ -24,6 +24,17 @@ func Resource(resource string) schema.GroupResource {
 	return GroupVersion.WithResource(resource).GroupResource()
 }
 
+// TeardownPolicy is used for deciding what happens to the generated resources when the API key Secret is unlabeled.
+// +kubebuilder:validation:Enum=Retain;Delete
+type TeardownPolicy string
+
+const (
+	// TeardownPolicyRetain keeps the generated resources in place
+	TeardownPolicyRetain TeardownPolicy = "Retain"
+	// TeardownPolicyDelete deletes the generated resources
+	TeardownPolicyDelete TeardownPolicy = "Delete"
+)
+
 type (
 	OdhNimAppSpecApiKey struct {
 		// +kubebuilder:default=true
@@ -43,16 +54,41 @@ type (
 		ConfigMapRef *corev1.ObjectReference `json:"configMapRef,omitempty"`
 	}
 
+	OdhNimAppSpecSchedule struct {
+		// +kubebuilder:default="24h"
+		// +kubebuilder:validation:Optional
+		// +operator-sdk:csv:customresourcedefinitions:type=spec,xDescriptors={"urn:alm:descriptor:com.tectonic.ui:text"}
+		Interval *metav1.Duration `json:"interval,omitempty"`
+		// +kubebuilder:validation:Optional
+		// +operator-sdk:csv:customresourcedefinitions:type=spec,xDescriptors={"urn:alm:descriptor:com.tectonic.ui:text"}
+		Cron string `json:"cron,omitempty"`
+	}
+
 	OdhNimAppSpec struct {
 		ApiKey  OdhNimAppSpecApiKey  `json:"apiKey"`
 		Content OdhNimAppSpecContent `json:"content"`
+		// +kubebuilder:validation:Optional
 		// +operator-sdk:csv:customresourcedefinitions:type=spec,xDescriptors={"urn:alm:descriptor:com.tectonic.ui:hidden"}
-		TemplateRef *corev1.ObjectReference `json:"templateRef"`
+		TemplateRef *corev1.ObjectReference `json:"templateRef,omitempty"`
+		// +kubebuilder:default=Retain
+		// +kubebuilder:validation:Optional
+		// +operator-sdk:csv:customresourcedefinitions:type=spec,xDescriptors={"urn:alm:descriptor:com.tectonic.ui:select:Retain","urn:alm:descriptor:com.tectonic.ui:select:Delete"}
+		TeardownPolicy TeardownPolicy `json:"teardownPolicy,omitempty"`
+		// +kubebuilder:validation:Optional
+		Schedule OdhNimAppSpecSchedule `json:"schedule,omitempty"`
 	}
 
 	OdhNimAppStatus struct {
 		// +operator-sdk:csv:customresourcedefinitions:type=status,xDescriptors={"urn:alm:descriptor:io.kubernetes.conditions"}
 		Conditions []metav1.Condition `json:"conditions,omitempty"`
+		// +operator-sdk:csv:customresourcedefinitions:type=status,xDescriptors={"urn:alm:descriptor:text"}
+		LastValidationTime *metav1.Time `json:"lastValidationTime,omitempty"`
+		// +operator-sdk:csv:customresourcedefinitions:type=status,xDescriptors={"urn:alm:descriptor:text"}
+		LastContentUpdateTime *metav1.Time `json:"lastContentUpdateTime,omitempty"`
+		// +operator-sdk:csv:customresourcedefinitions:type=status,xDescriptors={"urn:alm:descriptor:text"}
+		ObservedValidateRequestedAt string `json:"observedValidateRequestedAt,omitempty"`
+		// +operator-sdk:csv:customresourcedefinitions:type=status,xDescriptors={"urn:alm:descriptor:text"}
+		ObservedRefreshRequestedAt string `json:"observedRefreshRequestedAt,omitempty"`
 	}
 
 	// OdhNimApp is used for activating NIM integration reconciliation in Open Data Hub.
@@ -81,6 +81,7 @@ func (in *OdhNimAppSpec) DeepCopyInto(out *OdhNimAppSpec) {
 		*out = new(v1.ObjectReference)
 		**out = **in
 	}
+	in.Schedule.DeepCopyInto(&out.Schedule)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OdhNimAppSpec.
@@ -133,6 +134,26 @@ func (in *OdhNimAppSpecContent) DeepCopy() *OdhNimAppSpecContent {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OdhNimAppSpecSchedule) DeepCopyInto(out *OdhNimAppSpecSchedule) {
+	*out = *in
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OdhNimAppSpecSchedule.
+func (in *OdhNimAppSpecSchedule) DeepCopy() *OdhNimAppSpecSchedule {
+	if in == nil {
+		return nil
+	}
+	out := new(OdhNimAppSpecSchedule)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *OdhNimAppStatus) DeepCopyInto(out *OdhNimAppStatus) {
 	*out = *in
@@ -143,6 +164,14 @@ func (in *OdhNimAppStatus) DeepCopyInto(out *OdhNimAppStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.LastValidationTime != nil {
+		in, out := &in.LastValidationTime, &out.LastValidationTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastContentUpdateTime != nil {
+		in, out := &in.LastContentUpdateTime, &out.LastContentUpdateTime
+		*out = (*in).DeepCopy()
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OdhNimAppStatus.
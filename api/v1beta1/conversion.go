@@ -0,0 +1,6 @@
+// Copyright (c) 2024 Red Hat, Inc.
+
+package v1beta1
+
+// Hub marks v1beta1 as the conversion hub, other versions convert to and from it
+func (*OdhNimApp) Hub() {}
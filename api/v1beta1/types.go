@@ -0,0 +1,231 @@
+// Copyright (c) 2024 Red Hat, Inc.
+
+package v1beta1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/scheme"
+)
+
+// +groupName=nim.opendatahub.io
+// +kubebuilder:object:generate=true
+// +kubebuilder:validation:Required
+
+var (
+	GroupVersion  = schema.GroupVersion{Group: "nim.opendatahub.io", Version: "v1beta1"}
+	schemeBuilder = &scheme.Builder{GroupVersion: GroupVersion}
+	Install       = schemeBuilder.AddToScheme
+)
+
+// Resource takes an unqualified resource and returns a Group qualified GroupResource
+func Resource(resource string) schema.GroupResource {
+	return GroupVersion.WithResource(resource).GroupResource()
+}
+
+// TeardownPolicy is used for deciding what happens to the generated resources when the API key Secret is unlabeled.
+// +kubebuilder:validation:Enum=Retain;Delete
+type TeardownPolicy string
+
+const (
+	// TeardownPolicyRetain keeps the generated resources in place
+	TeardownPolicyRetain TeardownPolicy = "Retain"
+	// TeardownPolicyDelete deletes the generated resources
+	TeardownPolicyDelete TeardownPolicy = "Delete"
+)
+
+// WarmupPhase is used for reporting the progress of a model cache warm-up Job.
+// +kubebuilder:validation:Enum=Running;Succeeded;Failed;Skipped
+type WarmupPhase string
+
+const (
+	// WarmupPhaseRunning is used while the warm-up Job is running
+	WarmupPhaseRunning WarmupPhase = "Running"
+	// WarmupPhaseSucceeded is used once the model is cached
+	WarmupPhaseSucceeded WarmupPhase = "Succeeded"
+	// WarmupPhaseFailed is used when the warm-up Job failed or the model is not in the catalog
+	WarmupPhaseFailed WarmupPhase = "Failed"
+	// WarmupPhaseSkipped is used when the model has no version to warm up
+	WarmupPhaseSkipped WarmupPhase = "Skipped"
+)
+
+type (
+	OdhNimAppSpecApiKey struct {
+		// +kubebuilder:default=true
+		// +operator-sdk:csv:customresourcedefinitions:type=spec,xDescriptors={"urn:alm:descriptor:com.tectonic.ui:booleanSwitch"}
+		Validate bool `json:"validate"`
+		// +kubebuilder:validation:Optional
+		// +operator-sdk:csv:customresourcedefinitions:type=spec,xDescriptors={"urn:alm:descriptor:com.tectonic.ui:hidden"}
+		SecretRef *corev1.ObjectReference `json:"secretRef,omitempty"`
+	}
+
+	OdhNimAppSpecContent struct {
+		// +kubebuilder:default=true
+		// +operator-sdk:csv:customresourcedefinitions:type=spec,xDescriptors={"urn:alm:descriptor:com.tectonic.ui:booleanSwitch"}
+		Update bool `json:"update"`
+	}
+
+	OdhNimAppSpecSchedule struct {
+		// +kubebuilder:default="24h"
+		// +kubebuilder:validation:Optional
+		// +operator-sdk:csv:customresourcedefinitions:type=spec,xDescriptors={"urn:alm:descriptor:com.tectonic.ui:text"}
+		Interval *metav1.Duration `json:"interval,omitempty"`
+		// +kubebuilder:validation:Optional
+		// +operator-sdk:csv:customresourcedefinitions:type=spec,xDescriptors={"urn:alm:descriptor:com.tectonic.ui:text"}
+		Cron string `json:"cron,omitempty"`
+	}
+
+	OdhNimAppSpecStorage struct {
+		// +kubebuilder:validation:Optional
+		// +operator-sdk:csv:customresourcedefinitions:type=spec,xDescriptors={"urn:alm:descriptor:io.kubernetes:StorageClass"}
+		StorageClassName *string `json:"storageClassName,omitempty"`
+		// +kubebuilder:default="50Gi"
+		// +kubebuilder:validation:Optional
+		// +operator-sdk:csv:customresourcedefinitions:type=spec,xDescriptors={"urn:alm:descriptor:com.tectonic.ui:text"}
+		Size *resource.Quantity `json:"size,omitempty"`
+		// +kubebuilder:default=ReadWriteMany
+		// +kubebuilder:validation:Enum=ReadWriteMany;ReadWriteOnce
+		// +kubebuilder:validation:Optional
+		// +operator-sdk:csv:customresourcedefinitions:type=spec,xDescriptors={"urn:alm:descriptor:com.tectonic.ui:select:ReadWriteMany","urn:alm:descriptor:com.tectonic.ui:select:ReadWriteOnce"}
+		AccessMode corev1.PersistentVolumeAccessMode `json:"accessMode,omitempty"`
+	}
+
+	OdhNimAppSpecWarmup struct {
+		// +kubebuilder:validation:Optional
+		// +operator-sdk:csv:customresourcedefinitions:type=spec,xDescriptors={"urn:alm:descriptor:com.tectonic.ui:text"}
+		Models []string `json:"models,omitempty"`
+	}
+
+	OdhNimAppSpecPropagation struct {
+		// +kubebuilder:validation:Optional
+		// +operator-sdk:csv:customresourcedefinitions:type=spec,xDescriptors={"urn:alm:descriptor:com.tectonic.ui:selector:core:v1:Namespace"}
+		NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+		// +kubebuilder:validation:Optional
+		// +operator-sdk:csv:customresourcedefinitions:type=spec,xDescriptors={"urn:alm:descriptor:com.tectonic.ui:text"}
+		Namespaces []string `json:"namespaces,omitempty"`
+	}
+
+	OdhNimAppSpecOffline struct {
+		// +kubebuilder:validation:Optional
+		// +operator-sdk:csv:customresourcedefinitions:type=spec,xDescriptors={"urn:alm:descriptor:io.kubernetes:ConfigMap"}
+		CatalogRef *corev1.ObjectReference `json:"catalogRef,omitempty"`
+	}
+
+	OdhNimAppSpec struct {
+		ApiKey  OdhNimAppSpecApiKey  `json:"apiKey"`
+		Content OdhNimAppSpecContent `json:"content"`
+		// +kubebuilder:default=Retain
+		// +kubebuilder:validation:Optional
+		// +operator-sdk:csv:customresourcedefinitions:type=spec,xDescriptors={"urn:alm:descriptor:com.tectonic.ui:select:Retain","urn:alm:descriptor:com.tectonic.ui:select:Delete"}
+		TeardownPolicy TeardownPolicy `json:"teardownPolicy,omitempty"`
+		// +kubebuilder:validation:Optional
+		Schedule OdhNimAppSpecSchedule `json:"schedule,omitempty"`
+		// +kubebuilder:validation:Optional
+		Propagation OdhNimAppSpecPropagation `json:"propagation,omitempty"`
+		// +kubebuilder:validation:Optional
+		Storage OdhNimAppSpecStorage `json:"storage,omitempty"`
+		// +kubebuilder:validation:Optional
+		Warmup OdhNimAppSpecWarmup `json:"warmup,omitempty"`
+		// +kubebuilder:validation:Optional
+		Offline OdhNimAppSpecOffline `json:"offline,omitempty"`
+		// +kubebuilder:validation:Optional
+		// +operator-sdk:csv:customresourcedefinitions:type=spec,xDescriptors={"urn:alm:descriptor:com.tectonic.ui:text"}
+		Mirrors map[string]string `json:"mirrors,omitempty"`
+	}
+
+	OdhNimAppStatusWarmup struct {
+		Model   string      `json:"model"`
+		Version string      `json:"version,omitempty"`
+		Phase   WarmupPhase `json:"phase"`
+		// +kubebuilder:validation:Optional
+		JobRef *corev1.ObjectReference `json:"jobRef,omitempty"`
+		// +kubebuilder:validation:Optional
+		CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+		// +kubebuilder:validation:Optional
+		Message string `json:"message,omitempty"`
+	}
+
+	OdhNimAppStatusCatalogDiff struct {
+		Time    metav1.Time `json:"time"`
+		Summary string      `json:"summary"`
+		// +kubebuilder:validation:Optional
+		AddedModels []string `json:"addedModels,omitempty"`
+		// +kubebuilder:validation:Optional
+		RemovedModels []string `json:"removedModels,omitempty"`
+		// +kubebuilder:validation:Optional
+		UpdatedModels []string `json:"updatedModels,omitempty"`
+		// +kubebuilder:validation:Optional
+		HistoryRef *corev1.ObjectReference `json:"historyRef,omitempty"`
+	}
+
+	OdhNimAppStatusImageMirror struct {
+		Source string `json:"source"`
+		Mirror string `json:"mirror"`
+		Origin string `json:"origin"`
+		Images int32  `json:"images"`
+	}
+
+	OdhNimAppStatus struct {
+		// +operator-sdk:csv:customresourcedefinitions:type=status,xDescriptors={"urn:alm:descriptor:text"}
+		ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+		// +operator-sdk:csv:customresourcedefinitions:type=status,xDescriptors={"urn:alm:descriptor:io.kubernetes.conditions"}
+		Conditions []metav1.Condition `json:"conditions,omitempty"`
+		// +operator-sdk:csv:customresourcedefinitions:type=status,xDescriptors={"urn:alm:descriptor:text"}
+		TemplateRef *corev1.ObjectReference `json:"templateRef,omitempty"`
+		// +operator-sdk:csv:customresourcedefinitions:type=status,xDescriptors={"urn:alm:descriptor:text"}
+		ConfigMapRef *corev1.ObjectReference `json:"configMapRef,omitempty"`
+		// +operator-sdk:csv:customresourcedefinitions:type=status,xDescriptors={"urn:alm:descriptor:text"}
+		LastValidationTime *metav1.Time `json:"lastValidationTime,omitempty"`
+		// +operator-sdk:csv:customresourcedefinitions:type=status,xDescriptors={"urn:alm:descriptor:text"}
+		LastContentUpdateTime *metav1.Time `json:"lastContentUpdateTime,omitempty"`
+		// +operator-sdk:csv:customresourcedefinitions:type=status,xDescriptors={"urn:alm:descriptor:text"}
+		ObservedValidateRequestedAt string `json:"observedValidateRequestedAt,omitempty"`
+		// +operator-sdk:csv:customresourcedefinitions:type=status,xDescriptors={"urn:alm:descriptor:text"}
+		ObservedRefreshRequestedAt string `json:"observedRefreshRequestedAt,omitempty"`
+		// +operator-sdk:csv:customresourcedefinitions:type=status,xDescriptors={"urn:alm:descriptor:text"}
+		ApiKeyHash string `json:"apiKeyHash,omitempty"`
+		// +operator-sdk:csv:customresourcedefinitions:type=status,xDescriptors={"urn:alm:descriptor:text"}
+		Warmups []OdhNimAppStatusWarmup `json:"warmups,omitempty"`
+		// +operator-sdk:csv:customresourcedefinitions:type=status,xDescriptors={"urn:alm:descriptor:text"}
+		LastCatalogDiff *OdhNimAppStatusCatalogDiff `json:"lastCatalogDiff,omitempty"`
+		// +operator-sdk:csv:customresourcedefinitions:type=status,xDescriptors={"urn:alm:descriptor:text"}
+		ConsecutiveContentFailures int32 `json:"consecutiveContentFailures,omitempty"`
+		// +operator-sdk:csv:customresourcedefinitions:type=status,xDescriptors={"urn:alm:descriptor:text"}
+		ContentRetryTime *metav1.Time `json:"contentRetryTime,omitempty"`
+		// +operator-sdk:csv:customresourcedefinitions:type=status,xDescriptors={"urn:alm:descriptor:text"}
+		ImageMirrors []OdhNimAppStatusImageMirror `json:"imageMirrors,omitempty"`
+	}
+
+	// OdhNimApp is used for activating NIM integration reconciliation in Open Data Hub.
+	//
+	// +kubebuilder:object:root=true
+	// +kubebuilder:resource:shortName=ona
+	// +kubebuilder:storageversion
+	// +kubebuilder:subresource:status
+	// +kubebuilder:printcolumn:name="Validated",type="string",JSONPath=".status.conditions[?(@.type==\"ApiKeyValidated\")].status",description="The validation status of the API Key"
+	// +kubebuilder:printcolumn:name="Updated",type="string",JSONPath=".status.conditions[?(@.type==\"ContentUpdated\")].status",description="The status of the last content update"
+	// +operator-sdk:csv:customresourcedefinitions:displayName="ODH NIM App"
+	// +operator-sdk:csv:customresourcedefinitions:resources={{OdhNimApp,nim.opendatahub.io/v1beta1},{PersistentVolumeClaim,v1,nim-pvc},{Secret,v1,ngc-secret},{Secret,v1,nvidia-nim-secrets},{ConfigMap,v1,odh-nim-app-content},{Template,template.openshift.io/v1,nvidia-nim-serving-template}}
+	OdhNimApp struct {
+		metav1.TypeMeta   `json:",inline"`
+		metav1.ObjectMeta `json:"metadata,omitempty"`
+		Spec              OdhNimAppSpec `json:"spec"`
+		// +kubebuilder:validation:Optional
+		Status OdhNimAppStatus `json:"status,omitempty"`
+	}
+
+	// OdhNimAppList is used for encapsulating OdhNimApp items.
+	//
+	// +kubebuilder:object:root=true
+	OdhNimAppList struct {
+		metav1.TypeMeta `json:",inline"`
+		metav1.ListMeta `json:"metadata,omitempty"`
+		Items           []OdhNimApp `json:"items"`
+	}
+)
+
+func init() {
+	schemeBuilder.Register(&OdhNimApp{}, &OdhNimAppList{})
+}
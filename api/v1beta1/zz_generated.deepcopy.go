@@ -0,0 +1,382 @@
+//go:build !ignore_autogenerated
+
+// Copyright (c) 2024 Red Hat, Inc.
+
+// Code generated by controller-gen. DO NOT EDIT.
+
+package v1beta1
+
+import (
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+)
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OdhNimApp) DeepCopyInto(out *OdhNimApp) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OdhNimApp.
+func (in *OdhNimApp) DeepCopy() *OdhNimApp {
+	if in == nil {
+		return nil
+	}
+	out := new(OdhNimApp)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *OdhNimApp) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OdhNimAppList) DeepCopyInto(out *OdhNimAppList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]OdhNimApp, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OdhNimAppList.
+func (in *OdhNimAppList) DeepCopy() *OdhNimAppList {
+	if in == nil {
+		return nil
+	}
+	out := new(OdhNimAppList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *OdhNimAppList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OdhNimAppSpec) DeepCopyInto(out *OdhNimAppSpec) {
+	*out = *in
+	in.ApiKey.DeepCopyInto(&out.ApiKey)
+	out.Content = in.Content
+	in.Schedule.DeepCopyInto(&out.Schedule)
+	in.Propagation.DeepCopyInto(&out.Propagation)
+	in.Storage.DeepCopyInto(&out.Storage)
+	in.Warmup.DeepCopyInto(&out.Warmup)
+	in.Offline.DeepCopyInto(&out.Offline)
+	if in.Mirrors != nil {
+		in, out := &in.Mirrors, &out.Mirrors
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OdhNimAppSpec.
+func (in *OdhNimAppSpec) DeepCopy() *OdhNimAppSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(OdhNimAppSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OdhNimAppSpecApiKey) DeepCopyInto(out *OdhNimAppSpecApiKey) {
+	*out = *in
+	if in.SecretRef != nil {
+		in, out := &in.SecretRef, &out.SecretRef
+		*out = new(v1.ObjectReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OdhNimAppSpecApiKey.
+func (in *OdhNimAppSpecApiKey) DeepCopy() *OdhNimAppSpecApiKey {
+	if in == nil {
+		return nil
+	}
+	out := new(OdhNimAppSpecApiKey)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OdhNimAppSpecContent) DeepCopyInto(out *OdhNimAppSpecContent) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OdhNimAppSpecContent.
+func (in *OdhNimAppSpecContent) DeepCopy() *OdhNimAppSpecContent {
+	if in == nil {
+		return nil
+	}
+	out := new(OdhNimAppSpecContent)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OdhNimAppSpecOffline) DeepCopyInto(out *OdhNimAppSpecOffline) {
+	*out = *in
+	if in.CatalogRef != nil {
+		in, out := &in.CatalogRef, &out.CatalogRef
+		*out = new(v1.ObjectReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OdhNimAppSpecOffline.
+func (in *OdhNimAppSpecOffline) DeepCopy() *OdhNimAppSpecOffline {
+	if in == nil {
+		return nil
+	}
+	out := new(OdhNimAppSpecOffline)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OdhNimAppSpecPropagation) DeepCopyInto(out *OdhNimAppSpecPropagation) {
+	*out = *in
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OdhNimAppSpecPropagation.
+func (in *OdhNimAppSpecPropagation) DeepCopy() *OdhNimAppSpecPropagation {
+	if in == nil {
+		return nil
+	}
+	out := new(OdhNimAppSpecPropagation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OdhNimAppSpecSchedule) DeepCopyInto(out *OdhNimAppSpecSchedule) {
+	*out = *in
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OdhNimAppSpecSchedule.
+func (in *OdhNimAppSpecSchedule) DeepCopy() *OdhNimAppSpecSchedule {
+	if in == nil {
+		return nil
+	}
+	out := new(OdhNimAppSpecSchedule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OdhNimAppSpecStorage) DeepCopyInto(out *OdhNimAppSpecStorage) {
+	*out = *in
+	if in.StorageClassName != nil {
+		in, out := &in.StorageClassName, &out.StorageClassName
+		*out = new(string)
+		**out = **in
+	}
+	if in.Size != nil {
+		in, out := &in.Size, &out.Size
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OdhNimAppSpecStorage.
+func (in *OdhNimAppSpecStorage) DeepCopy() *OdhNimAppSpecStorage {
+	if in == nil {
+		return nil
+	}
+	out := new(OdhNimAppSpecStorage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OdhNimAppSpecWarmup) DeepCopyInto(out *OdhNimAppSpecWarmup) {
+	*out = *in
+	if in.Models != nil {
+		in, out := &in.Models, &out.Models
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OdhNimAppSpecWarmup.
+func (in *OdhNimAppSpecWarmup) DeepCopy() *OdhNimAppSpecWarmup {
+	if in == nil {
+		return nil
+	}
+	out := new(OdhNimAppSpecWarmup)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OdhNimAppStatus) DeepCopyInto(out *OdhNimAppStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.TemplateRef != nil {
+		in, out := &in.TemplateRef, &out.TemplateRef
+		*out = new(v1.ObjectReference)
+		**out = **in
+	}
+	if in.ConfigMapRef != nil {
+		in, out := &in.ConfigMapRef, &out.ConfigMapRef
+		*out = new(v1.ObjectReference)
+		**out = **in
+	}
+	if in.LastValidationTime != nil {
+		in, out := &in.LastValidationTime, &out.LastValidationTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastContentUpdateTime != nil {
+		in, out := &in.LastContentUpdateTime, &out.LastContentUpdateTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Warmups != nil {
+		in, out := &in.Warmups, &out.Warmups
+		*out = make([]OdhNimAppStatusWarmup, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LastCatalogDiff != nil {
+		in, out := &in.LastCatalogDiff, &out.LastCatalogDiff
+		*out = new(OdhNimAppStatusCatalogDiff)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ContentRetryTime != nil {
+		in, out := &in.ContentRetryTime, &out.ContentRetryTime
+		*out = (*in).DeepCopy()
+	}
+	if in.ImageMirrors != nil {
+		in, out := &in.ImageMirrors, &out.ImageMirrors
+		*out = make([]OdhNimAppStatusImageMirror, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OdhNimAppStatus.
+func (in *OdhNimAppStatus) DeepCopy() *OdhNimAppStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(OdhNimAppStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OdhNimAppStatusCatalogDiff) DeepCopyInto(out *OdhNimAppStatusCatalogDiff) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+	if in.AddedModels != nil {
+		in, out := &in.AddedModels, &out.AddedModels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.RemovedModels != nil {
+		in, out := &in.RemovedModels, &out.RemovedModels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.UpdatedModels != nil {
+		in, out := &in.UpdatedModels, &out.UpdatedModels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.HistoryRef != nil {
+		in, out := &in.HistoryRef, &out.HistoryRef
+		*out = new(v1.ObjectReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OdhNimAppStatusCatalogDiff.
+func (in *OdhNimAppStatusCatalogDiff) DeepCopy() *OdhNimAppStatusCatalogDiff {
+	if in == nil {
+		return nil
+	}
+	out := new(OdhNimAppStatusCatalogDiff)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OdhNimAppStatusImageMirror) DeepCopyInto(out *OdhNimAppStatusImageMirror) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OdhNimAppStatusImageMirror.
+func (in *OdhNimAppStatusImageMirror) DeepCopy() *OdhNimAppStatusImageMirror {
+	if in == nil {
+		return nil
+	}
+	out := new(OdhNimAppStatusImageMirror)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OdhNimAppStatusWarmup) DeepCopyInto(out *OdhNimAppStatusWarmup) {
+	*out = *in
+	if in.JobRef != nil {
+		in, out := &in.JobRef, &out.JobRef
+		*out = new(v1.ObjectReference)
+		**out = **in
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OdhNimAppStatusWarmup.
+func (in *OdhNimAppStatusWarmup) DeepCopy() *OdhNimAppStatusWarmup {
+	if in == nil {
+		return nil
+	}
+	out := new(OdhNimAppStatusWarmup)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -3,6 +3,8 @@
 package main
 
 import (
+	"github.com/opendatahub-io/odh-nim-operator/pkg/controllers"
+	"github.com/opendatahub-io/odh-nim-operator/pkg/ngc"
 	"github.com/opendatahub-io/odh-nim-operator/pkg/operator"
 	"github.com/opendatahub-io/odh-nim-operator/pkg/version"
 	"github.com/spf13/cobra"
@@ -44,6 +46,81 @@ func init() {
 		"enable-webhooks",
 		false,
 		"Enable admission webhooks")
+	cmd.Flags().StringVar(
+		&oper.Options.OperatorUsername,
+		"operator-username",
+		"",
+		"The username of the operator service account, i.e. system:serviceaccount:<namespace>:<name>, required by the admission webhooks.")
+	cmd.Flags().BoolVar(
+		&oper.Options.EnablePrometheusRules,
+		"enable-prometheus-rules",
+		false,
+		"Enable the PrometheusRule alerting on the NIM integration health, requires the Prometheus Operator.")
+	cmd.Flags().StringVar(
+		&oper.Options.PrometheusRuleNamespace,
+		"prometheus-rule-namespace",
+		"",
+		"The namespace of the PrometheusRule alerting on the NIM integration health, defaults to the operator namespace.")
+	cmd.Flags().StringVar(
+		&oper.Options.PropagationSelector,
+		"propagation-namespace-selector",
+		controllers.DefaultPropagationSelector,
+		"The label selector of the namespaces the NGC secrets are copied to by default, empty disables it.")
+	cmd.Flags().StringVar(
+		&oper.Options.TrustedCaBundle,
+		"trusted-ca-bundle",
+		"",
+		"The <namespace>/<name> of a ConfigMap holding a CA bundle to trust when connecting to NGC, reloaded on changes.")
+	cmd.Flags().StringVar(
+		&oper.Options.OfflineCatalog,
+		"offline-catalog",
+		"",
+		"The path of an offline NIM catalog, a JSON file, tarball, or directory, loaded instead of fetching it from NGC.")
+	cmd.Flags().BoolVar(
+		&oper.Options.SkipApiKeyValidation,
+		"skip-api-key-validation",
+		false,
+		"Only verify the API keys are set, for disconnected clusters with no NGC authentication endpoint to delegate to.")
+	cmd.Flags().StringVar(
+		&oper.Options.NgcOptions.AuthUrl,
+		"ngc-auth-url",
+		ngc.DefaultAuthUrl,
+		"The NGC authentication endpoint used for validating API keys, can be delegated to a local endpoint.")
+	cmd.Flags().StringVar(
+		&oper.Options.NgcOptions.ApiUrl,
+		"ngc-api-url",
+		ngc.DefaultApiUrl,
+		"The NGC API endpoint used for fetching the NIM catalog.")
+	cmd.Flags().DurationVar(
+		&oper.Options.NgcOptions.Timeout,
+		"ngc-timeout",
+		ngc.DefaultTimeout,
+		"The timeout for requests sent to NGC.")
+	cmd.Flags().Float64Var(
+		&oper.Options.NgcOptions.RateLimit,
+		"ngc-rate-limit",
+		ngc.DefaultRateLimit,
+		"The requests per second sent to NGC, shared by all OdhNimApps, a negative value disables the limit.")
+	cmd.Flags().IntVar(
+		&oper.Options.NgcOptions.RateBurst,
+		"ngc-rate-burst",
+		ngc.DefaultRateBurst,
+		"The number of requests sent to NGC at once before the rate limit applies.")
+	cmd.Flags().DurationVar(
+		&oper.Options.NgcOptions.CatalogTTL,
+		"ngc-catalog-ttl",
+		controllers.DefaultCatalogTTL,
+		"The time a NIM catalog fetched from NGC is shared by the OdhNimApps, zero or negative disables it.")
+	cmd.Flags().StringVar(
+		&oper.Options.NgcOptions.CaFile,
+		"ngc-ca-file",
+		"",
+		"A PEM encoded CA bundle file to trust in addition to the system pool when connecting to NGC.")
+	cmd.Flags().BoolVar(
+		&oper.Options.NgcOptions.InsecureSkipVerify,
+		"ngc-insecure-skip-tls-verify",
+		false,
+		"Skip TLS verification when connecting to NGC, use only for testing.")
 
 	cmd.RunE = oper.Run
 	cmd.Version = version.Get().GitVersion
@@ -4,23 +4,58 @@ package controllers
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/opendatahub-io/odh-nim-operator/api/v1beta1"
+	"github.com/opendatahub-io/odh-nim-operator/pkg/metrics"
+	"github.com/opendatahub-io/odh-nim-operator/pkg/ngc"
+	"github.com/opendatahub-io/odh-nim-operator/pkg/version"
+	templatev1 "github.com/openshift/api/template/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"time"
 )
 
+const ngcRetryInterval = 5 * time.Minute
+
 type AppController struct {
 	client.Client
-	Scheme *runtime.Scheme
+	Scheme    *runtime.Scheme
+	NgcClient *ngc.Client
+	Recorder  record.EventRecorder
+	// OfflineCatalog is the path of the offline catalog mounted in the operator pod, NGC is used if empty
+	OfflineCatalog string
+	// SkipApiKeyValidation only verifies the API keys are set, the keys are not reported as validated
+	SkipApiKeyValidation bool
 }
 
 // SetupWithManager is used for setting up the controller with a manager (check the init function)
 func (r *AppController) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	bldr, err := watchImageMirrors(mgr, ctrl.NewControllerManagedBy(mgr).
 		Named("odh-nim-app-controller").
-		Complete(r)
+		For(&v1beta1.OdhNimApp{}).
+		Owns(&corev1.ConfigMap{}).
+		Owns(&corev1.PersistentVolumeClaim{}).
+		Owns(&corev1.Secret{}).
+		Owns(&batchv1.Job{}).
+		Owns(&templatev1.Template{}), r.mapImageMirror)
+	if err != nil {
+		return err
+	}
+	return bldr.Complete(r)
 }
 
 // rbac markers are in controllers.go
@@ -29,53 +64,890 @@ func (r *AppController) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.R
 	logger := log.FromContext(ctx).WithName("app-controller")
 	ctx = log.IntoContext(ctx, logger)
 	// all funcs we invoke in this context should use 'logger := log.FromContext(ctx)' to get the correct logger
-	logger.V(1).Info(fmt.Sprintf("got request for Secret %s", req.NamespacedName))
-
-	// TODO write code
-	// 1. Fetch OdhNimApp
-	// 2. If OdhNimApp NOT found (deleted):
-	//		- Break reconciliation, we use the finalizer mechanism to cleanups
-	//
-	// 3. If in deletion process !OdhNimApp.DeletionTimestamp.IsZero() (note the !):
-	//		3.1 If has our finalizer "nim.opendatahub.io/cleanup_finalizer" (const in controllers.go):
-	//			- Tear down (if we want)
-	//			- Remove our finalizer
-	//		3.2 Break reconciliation
-	//
-	// 4. If doesn't have our finalizer "nim.opendatahub.io/cleanup_finalizer":
-	//		- Add the finalizer
-	//
-	// 5. If OdhNimApp.Spec.ApiKey.Validate is True (defaults to true):
-	//		5.1 Validate the API Key!!
-	// 		5.2 Patch OdhNimApp.Status.Condition[Type=ApiKeyValidated] to True/False based on the validation (consts in controllers.go)
-	//		5.3 Patch OdhNimApp.Spec.ApiKey.Validate to False (but store the original value for step 7)
-	//		5.4 If the validation NOT successful:
-	//			- Do we want to tear down?
-	//			- Break reconciliation
-	//
-	// 6. Reconcile the Template, OdhNimApp.Spec.TemplateRef, if empty, create and patch the reference
-	//
-	// 7. If OdhNimApp.Spec.ApiKey.Validate WAS True (before 5.3) OR
-	//	OdhNimApp.Spec.Content.Update IS True OR
-	//	OdhNimApp.Spec.Content.ConfigMapRef is empty (we haven't created it yet):
-	//		7.1 Fetch NIM Images and models
-	//		7.2 Patch OdhNimApp.Status.Condition[Type=ContentUpdated] to True/False based on the fetch status (consts in controllers.go)
-	//		7.3 Patch OdhNimApp.Spec.Content.Update to False (if wasn't false to begin with)
-	//		7.4 If the fetching was successful:
-	//			- Reconcile the content ConfigMap with the updated data
-	//			- Patch the OdhNimApp.Spec.Content.ConfigMapRef with the reference to the ConfigMap if empty
-	//
-	// 8.  Reconcile a daily recurring Cron Job owned by this OdhNimApp, patching OdhNimApp.Spec.Content.Update to True
-
-	return ctrl.Result{}, nil
+	logger.V(1).Info(fmt.Sprintf("got request for OdhNimApp %s", req.NamespacedName))
+
+	app := &v1beta1.OdhNimApp{}
+	if err := r.Get(ctx, req.NamespacedName, app); err != nil {
+		if k8serrors.IsNotFound(err) {
+			// deleted, cleanups are done using the finalizer mechanism
+			logger.V(1).Info("OdhNimApp not found, probably deleted")
+			metrics.DeleteApp(req.Namespace, req.Name)
+			return ctrl.Result{}, nil
+		}
+		logger.Error(err, "failed fetching OdhNimApp")
+		return ctrl.Result{}, err
+	}
+
+	// deletion in progress, resources owned by the OdhNimApp are garbage collected by the cluster, the copies propagated
+	// to other namespaces can't be owned, the finalizer is only removed once all of them are deleted
+	if !app.DeletionTimestamp.IsZero() {
+		metrics.SetDeleting(app.Namespace, app.Name, app.DeletionTimestamp.Time)
+		if controllerutil.ContainsFinalizer(app, Finalizer_NimAppCleanup) {
+			if err := deletePropagated(ctx, r.Client, app.Namespace, nil); err != nil {
+				logger.Error(err, "failed deleting propagated secrets")
+				return ctrl.Result{}, err
+			}
+			patch := client.MergeFrom(app.DeepCopy())
+			controllerutil.RemoveFinalizer(app, Finalizer_NimAppCleanup)
+			if err := r.Patch(ctx, app, patch); err != nil {
+				logger.Error(err, "failed removing finalizer")
+				return ctrl.Result{}, err
+			}
+			logger.Info("removed finalizer")
+		}
+		return ctrl.Result{}, nil
+	}
+
+	if !controllerutil.ContainsFinalizer(app, Finalizer_NimAppCleanup) {
+		patch := client.MergeFrom(app.DeepCopy())
+		controllerutil.AddFinalizer(app, Finalizer_NimAppCleanup)
+		if err := r.Patch(ctx, app, patch); err != nil {
+			logger.Error(err, "failed adding finalizer")
+			return ctrl.Result{}, err
+		}
+		logger.Info("added finalizer")
+	}
+
+	// validation was requested or is scheduled, store the original value as it also triggers a content update
+	now := time.Now()
+	validationRequested := app.Spec.ApiKey.Validate ||
+		isRequestPending(app, Annotation_ValidateRequestedAt, app.Status.ObservedValidateRequestedAt) ||
+		!now.Before(nextAppRun(ctx, app, app.Status.LastValidationTime))
+	if validationRequested {
+		validationStart := time.Now()
+		condition, apiKey, err := r.validateApiKey(ctx, app)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		metrics.ObserveApiKeyValidation(app.Namespace, app.Name, condition.Reason, time.Since(validationStart))
+		if condition.Status == metav1.ConditionFalse && app.Status.ApiKeyHash != "" {
+			// the generated secrets are only swapped to validated keys
+			condition.Message = fmt.Sprintf("%s, keeping the last known good API key", condition.Message)
+		}
+		retryValidation := condition.Reason == Reason_NgcUnreachable || condition.Reason == Reason_NgcRateLimited
+		recordConditionEvent(r.Recorder, app, condition)
+		if err = patchStatus(ctx, r.Client, app, func() {
+			setCondition(app, condition)
+			if !retryValidation {
+				app.Status.LastValidationTime = &metav1.Time{Time: now}
+				app.Status.ObservedValidateRequestedAt = app.Annotations[Annotation_ValidateRequestedAt]
+			}
+			if isApiKeyAccepted(condition) {
+				app.Status.ApiKeyHash = hashApiKey(apiKey)
+			}
+		}); err != nil {
+			logger.Error(err, "failed patching validation status")
+			return ctrl.Result{}, err
+		}
+
+		if condition.Reason == Reason_NgcRateLimited {
+			// keep the validation request for the next attempt, once the NGC client stops holding off requests
+			return ctrl.Result{RequeueAfter: rateLimitRetryDelay(r.NgcClient.RetryAfter())}, nil
+		}
+		if retryValidation {
+			// keep the validation request for the next attempt
+			return ctrl.Result{RequeueAfter: ngcRetryInterval}, nil
+		}
+
+		if app.Spec.ApiKey.Validate {
+			patch := client.MergeFrom(app.DeepCopy())
+			app.Spec.ApiKey.Validate = false
+			if err = r.Patch(ctx, app, patch); err != nil {
+				logger.Error(err, "failed resetting validation request")
+				return ctrl.Result{}, err
+			}
+		}
+	}
+
+	validated := meta.FindStatusCondition(app.Status.Conditions, Condition_ApiKeyValidated)
+	if validated != nil && validated.Reason == Reason_ApiKeyValidationSkipped {
+		// skipped validations report nothing about the key
+		metrics.DeleteApiKeyValid(app.Namespace, app.Name)
+	} else {
+		metrics.SetApiKeyValid(app.Namespace, app.Name, validated == nil || validated.Status != metav1.ConditionFalse)
+	}
+	if validated == nil || !isApiKeyAccepted(*validated) {
+		logger.Info("API key is not valid, skipping reconciliation")
+		// revalidate on schedule, a rejected key may be entitled later without the Secret changing
+		return ctrl.Result{RequeueAfter: requeueAfter(now, nextAppRun(ctx, app, app.Status.LastValidationTime))}, nil
+	}
+
+	if err := r.reconcileNgcSecrets(ctx, app); err != nil {
+		logger.Error(err, "failed reconciling NGC secrets")
+		return ctrl.Result{}, err
+	}
+
+	if err := r.reconcileNimPvc(ctx, app); err != nil {
+		logger.Error(err, "failed reconciling NIM PVC")
+		return ctrl.Result{}, err
+	}
+
+	if err := r.reconcileServingTemplate(ctx, app); err != nil {
+		logger.Error(err, "failed reconciling serving template")
+		return ctrl.Result{}, err
+	}
+
+	// the NIM ServingRuntimes follow the registry mirrors regardless of the content updates
+	mirrors, err := getImageMirrors(ctx, r.Client, app)
+	if err != nil {
+		logger.Error(err, "failed listing image mirrors")
+		return ctrl.Result{}, err
+	}
+	if err = rewriteServingRuntimes(ctx, r.Client, app.Namespace, mirrors); err != nil {
+		logger.Error(err, "failed rewriting serving runtime images")
+		return ctrl.Result{}, err
+	}
+
+	// content updates are held off by the circuit breaker, or NGC rate limits, unless explicitly requested
+	contentRequested := app.Spec.Content.Update ||
+		isRequestPending(app, Annotation_RefreshRequestedAt, app.Status.ObservedRefreshRequestedAt)
+	contentDue := validationRequested || app.Status.ConfigMapRef == nil ||
+		!now.Before(nextAppRun(ctx, app, app.Status.LastContentUpdateTime))
+	if contentRequested || (contentDue && !isContentCircuitOpen(app, now)) {
+		if err := r.reconcileContent(ctx, app, contentRequested); err != nil {
+			return ctrl.Result{}, err
+		}
+	} else if contentDue {
+		logger.Info("content update held off", "retry", app.Status.ContentRetryTime)
+	}
+
+	if err := r.reconcileWarmups(ctx, app); err != nil {
+		logger.Error(err, "failed reconciling model cache warm-ups")
+		return ctrl.Result{}, err
+	}
+
+	if err := r.reportContentMetrics(ctx, app); err != nil {
+		logger.Error(err, "failed reporting content metrics")
+		return ctrl.Result{}, err
+	}
+
+	// schedule the next validation and content update, failed content updates are retried
+	return ctrl.Result{RequeueAfter: requeueAfter(now,
+		nextAppRun(ctx, app, app.Status.LastValidationTime),
+		nextContentUpdate(ctx, app),
+	)}, nil
+}
+
+// validateApiKey is used for validating the API key referenced by the OdhNimApp against NGC, returns the condition
+// reflecting the validation result and the validated key, an error is only returned for unexpected failures that
+// should be retried
+func (r *AppController) validateApiKey(ctx context.Context, app *v1beta1.OdhNimApp) (metav1.Condition, string, error) {
+	logger := log.FromContext(ctx)
+
+	condition := metav1.Condition{
+		Type:    Condition_ApiKeyValidated,
+		Status:  metav1.ConditionTrue,
+		Reason:  Reason_ApiKeyValidatedSuccessfully,
+		Message: "API key validated successfully",
+	}
+
+	apiKey, err := r.getApiKey(ctx, app)
+	if err != nil {
+		if !k8serrors.IsNotFound(err) && !isMissingApiKey(err) {
+			logger.Error(err, "failed fetching API key")
+			return condition, "", err
+		}
+		logger.Info("API key validation failed", "reason", err.Error())
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = Reason_ApiKeyValidationFailed
+		condition.Message = err.Error()
+		return condition, "", nil
+	}
+
+	// disconnected clusters with no NGC authentication endpoint to delegate to can only verify the key is set
+	if r.SkipApiKeyValidation {
+		condition.Status = metav1.ConditionUnknown
+		condition.Reason = Reason_ApiKeyValidationSkipped
+		condition.Message = "API key validation skipped by the operator, only verified the API key is set"
+		return condition, apiKey, nil
+	}
+
+	if err = r.NgcClient.ValidateApiKey(ctx, apiKey); err != nil {
+		logger.Info("API key validation failed", "reason", err.Error())
+		condition.Message = err.Error()
+		if errors.Is(err, ngc.ErrInvalidApiKey) {
+			condition.Status = metav1.ConditionFalse
+			condition.Reason = Reason_ApiKeyInvalid
+		} else if errors.Is(err, ngc.ErrRateLimited) {
+			condition.Status = metav1.ConditionUnknown
+			condition.Reason = Reason_NgcRateLimited
+		} else {
+			condition.Status = metav1.ConditionUnknown
+			condition.Reason = Reason_NgcUnreachable
+		}
+	}
+
+	return condition, apiKey, nil
+}
+
+// reconcileContent is used for fetching the NIM images and models, reconciling the content ConfigMap, and reporting
+// the result in the OdhNimApp status, content update requests are reset or observed regardless of the result, the last
+// content update time is only set for successful updates. Failed updates keep the last known good content, reported as
+// degraded, failures delay the next attempt, and consecutive failures open the circuit breaker delaying the next
+// attempts further. Rate limited updates are not counted as failures, the next attempt is delayed until the NGC client
+// stops holding off requests.
+func (r *AppController) reconcileContent(ctx context.Context, app *v1beta1.OdhNimApp, requested bool) error {
+	logger := log.FromContext(ctx)
+
+	previous := &corev1.ConfigMap{}
+	if app.Status.ConfigMapRef != nil {
+		cmKey := client.ObjectKey{Name: app.Status.ConfigMapRef.Name, Namespace: app.Namespace}
+		if err := r.Get(ctx, cmKey, previous); err != nil && !k8serrors.IsNotFound(err) {
+			logger.Error(err, "failed fetching content configmap")
+			return err
+		}
+	}
+
+	condition := metav1.Condition{
+		Type:    Condition_ContentUpdated,
+		Status:  metav1.ConditionTrue,
+		Reason:  Reason_ContentUpdatedSuccessfully,
+		Message: "content updated successfully",
+	}
+
+	var cm *corev1.ConfigMap
+	var diff *catalogDiff
+	var rateLimited bool
+	var data map[string]string
+	var resolved map[imageMirror]int32
+	fetchStart := time.Now()
+	mirrors, err := getImageMirrors(ctx, r.Client, app)
+	if err == nil {
+		data, resolved, err = r.fetchContent(ctx, app, mirrors, requested)
+	}
+	if err == nil {
+		err = verifyContent(data, len(previous.Data), requested)
+	}
+	fetchResult := metrics.ResultSuccess
+	if err != nil {
+		fetchResult = metrics.ResultFailure
+	}
+	metrics.ObserveCatalogRefresh(app.Namespace, app.Name, fetchResult, time.Since(fetchStart))
+	if err != nil {
+		logger.Info("content fetch failed", "reason", err.Error())
+		rateLimited = errors.Is(err, ngc.ErrRateLimited)
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = Reason_ContentUpdateFailed
+		condition.Message = err.Error()
+		if len(previous.Data) > 0 && app.Status.LastContentUpdateTime != nil {
+			condition.Reason = Reason_ContentDegraded
+			condition.Message = fmt.Sprintf("%s, serving the last known good content from %s", condition.Message,
+				app.Status.LastContentUpdateTime.UTC().Format(time.RFC3339))
+		}
+	} else if cm, diff, err = r.reconcileContentConfigMap(ctx, app, data); err != nil {
+		logger.Error(err, "failed reconciling content configmap")
+		return err
+	}
+
+	updated := metav1.Now()
+	var historyRef *corev1.ObjectReference
+	if diff != nil {
+		diff.Time = updated.UTC().Format(time.RFC3339)
+		if historyRef, err = r.reconcileContentHistory(ctx, app, diff); err != nil {
+			logger.Error(err, "failed reconciling content history configmap")
+			return err
+		}
+		logger.Info("content catalog changed", "added", diff.Added, "removed", diff.Removed,
+			"updated", diff.updatedModels())
+		r.Recorder.Eventf(app, corev1.EventTypeNormal, Reason_ContentChanged,
+			"content catalog changed, %s", diff.summary())
+	}
+
+	var failures int32
+	var retryTime *metav1.Time
+	if cm == nil {
+		failures = app.Status.ConsecutiveContentFailures + 1
+		retryTime = contentRetryTime(updated.Time, failures)
+		if rateLimited {
+			failures = app.Status.ConsecutiveContentFailures
+			retryTime = &metav1.Time{Time: updated.Add(rateLimitRetryDelay(r.NgcClient.RetryAfter()))}
+		}
+		if retryTime != nil {
+			condition.Message = fmt.Sprintf("%s, retrying at %s", condition.Message,
+				retryTime.UTC().Format(time.RFC3339))
+		}
+	}
+
+	recordConditionEvent(r.Recorder, app, condition)
+	if err = patchStatus(ctx, r.Client, app, func() {
+		setCondition(app, condition)
+		app.Status.ObservedRefreshRequestedAt = app.Annotations[Annotation_RefreshRequestedAt]
+		app.Status.ConsecutiveContentFailures = failures
+		app.Status.ContentRetryTime = retryTime
+		if cm != nil {
+			app.Status.LastContentUpdateTime = &updated
+			app.Status.ConfigMapRef = &corev1.ObjectReference{Name: cm.Name, Namespace: cm.Namespace}
+			app.Status.ImageMirrors = reportImageMirrors(resolved)
+		}
+		if diff != nil {
+			app.Status.LastCatalogDiff = &v1beta1.OdhNimAppStatusCatalogDiff{
+				Time:          updated,
+				Summary:       diff.summary(),
+				AddedModels:   diff.Added,
+				RemovedModels: diff.Removed,
+				UpdatedModels: diff.updatedModels(),
+				HistoryRef:    historyRef,
+			}
+		}
+	}); err != nil {
+		logger.Error(err, "failed patching content status")
+		return err
+	}
+
+	// the spec is only patched for backward compatibility, requests made using annotations don't mutate it
+	if !app.Spec.Content.Update {
+		return nil
+	}
+	patch := client.MergeFrom(app.DeepCopy())
+	app.Spec.Content.Update = false
+	if err = r.Patch(ctx, app, patch); err != nil {
+		logger.Error(err, "failed patching content spec")
+		return err
+	}
+
+	return nil
+}
+
+// fetchContent is used for fetching the NIM models available for the API key referenced by the OdhNimApp, or loading
+// them from the offline catalog, returns the data for the content ConfigMap, every model is encoded as JSON keyed by
+// its name (see the ngc package for the schema), and the number of images resolved to every mirror. Images are
+// rewritten to their mirrors.
+func (r *AppController) fetchContent(ctx context.Context, app *v1beta1.OdhNimApp, mirrors []imageMirror,
+	requested bool) (map[string]string, map[imageMirror]int32, error) {
+	logger := log.FromContext(ctx)
+
+	models, err := r.getCatalog(ctx, app, requested)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	data := make(map[string]string, len(models))
+	resolved := map[imageMirror]int32{}
+	for _, model := range models {
+		if errs := validation.IsConfigMapKey(model.Name); len(errs) > 0 {
+			logger.Info("skipping model with an invalid name", "model", model.Name)
+			continue
+		}
+		if image, mirror := resolveImage(model.Image, mirrors); mirror != nil {
+			model.SourceImage, model.Image = model.Image, image
+			resolved[*mirror]++
+		}
+		encoded, err := json.Marshal(model)
+		if err != nil {
+			return nil, nil, err
+		}
+		data[model.Name] = string(encoded)
+	}
+
+	logger.V(1).Info(fmt.Sprintf("fetched %d models", len(data)))
+	return data, resolved, nil
+}
+
+// getCatalog is used for getting the NIM models, the offline catalog referenced by the OdhNimApp takes precedence over
+// the offline catalog mounted in the operator pod, NGC is only used if neither is set. The referenced offline catalog
+// is only read from the OdhNimApp namespace, and its content is not reported, it may not be readable by the users. The
+// NGC catalog is shared by the OdhNimApps entitled to it, requested content updates refresh it ignoring its TTL.
+func (r *AppController) getCatalog(ctx context.Context, app *v1beta1.OdhNimApp, refresh bool) ([]ngc.Model, error) {
+	if ref := app.Spec.Offline.CatalogRef; ref != nil {
+		if ref.Namespace != "" && ref.Namespace != app.Namespace {
+			return nil, fmt.Errorf("offline catalog %s must be in the namespace %s", ref.Name, app.Namespace)
+		}
+		key := client.ObjectKey{Name: ref.Name, Namespace: app.Namespace}
+		cm := &corev1.ConfigMap{}
+		if err := r.Get(ctx, key, cm); err != nil {
+			return nil, err
+		}
+		if version, found := cm.Annotations[Annotation_ContentSchemaVersion]; found && version != ngc.CatalogSchemaVersion {
+			return nil, fmt.Errorf("offline catalog %s has schema version %s, expected %s", key, version,
+				ngc.CatalogSchemaVersion)
+		}
+		models, err := ngc.DecodeCatalog(cm.Data)
+		if err != nil {
+			log.FromContext(ctx).Error(err, "failed decoding offline catalog")
+			return nil, fmt.Errorf("offline catalog %s is malformed", key)
+		}
+		return models, nil
+	}
+
+	if r.OfflineCatalog != "" {
+		return ngc.LoadCatalog(r.OfflineCatalog)
+	}
+
+	apiKey, err := r.getApiKey(ctx, app)
+	if err != nil {
+		return nil, err
+	}
+	if refresh {
+		return r.NgcClient.RefreshCatalog(ctx, apiKey)
+	}
+	return r.NgcClient.GetCatalog(ctx, apiKey)
+}
+
+// verifyContent is used for refusing to replace the last known good content with an empty or drastically shrunk
+// catalog, NGC may return partial results during outages, the catalog is accepted if the update was requested
+func verifyContent(data map[string]string, previousModels int, requested bool) error {
+	if requested || previousModels == 0 {
+		return nil
+	}
+	if len(data) == 0 {
+		return errors.New("refusing to replace the content with an empty catalog, request a refresh to accept it")
+	}
+	if 2*len(data) < previousModels {
+		return fmt.Errorf("refusing to replace the content of %d models with a catalog of %d models, request a "+
+			"refresh to accept it", previousModels, len(data))
+	}
+	return nil
+}
+
+// reconcileContentConfigMap is used for creating or patching the content ConfigMap owned by the OdhNimApp, returns the
+// diff between the previous and the new content, nil if the ConfigMap was created or the content didn't change
+func (r *AppController) reconcileContentConfigMap(ctx context.Context, app *v1beta1.OdhNimApp,
+	data map[string]string) (*corev1.ConfigMap, *catalogDiff, error) {
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: Name_ContentConfigMap, Namespace: app.Namespace}}
+	if app.Status.ConfigMapRef != nil && app.Status.ConfigMapRef.Name != "" {
+		cm.Name = app.Status.ConfigMapRef.Name
+	}
+
+	var previous map[string]string
+	result, err := controllerutil.CreateOrPatch(ctx, r.Client, cm, func() error {
+		previous = cm.Data
+		metav1.SetMetaDataAnnotation(&cm.ObjectMeta, Annotation_ContentSchemaVersion, ngc.CatalogSchemaVersion)
+		cm.Data = data
+		return controllerutil.SetControllerReference(app, cm, r.Scheme)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if result != controllerutil.OperationResultUpdated {
+		return cm, nil, nil
+	}
+	return cm, diffCatalogs(previous, data), nil
+}
+
+// reconcileContentHistory is used for recording a content diff in the content history ConfigMap owned by the
+// OdhNimApp, the ConfigMap keeps the last catalogHistoryLimit diffs
+func (r *AppController) reconcileContentHistory(
+	ctx context.Context, app *v1beta1.OdhNimApp, diff *catalogDiff) (*corev1.ObjectReference, error) {
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: Name_ContentHistory, Namespace: app.Namespace}}
+	if _, err := controllerutil.CreateOrPatch(ctx, r.Client, cm, func() error {
+		history, err := appendHistory(cm.Data[Key_ContentHistory], diff)
+		if err != nil {
+			return err
+		}
+		cm.Data = map[string]string{Key_ContentHistory: history}
+		return controllerutil.SetControllerReference(app, cm, r.Scheme)
+	}); err != nil {
+		return nil, err
+	}
+	return &corev1.ObjectReference{Name: cm.Name, Namespace: cm.Namespace}, nil
+}
+
+// reportContentMetrics is used for reporting the number of models and distinct images in the content ConfigMap, the
+// last successful content update time, and when the next one is due, the metrics are reported on every reconciliation
+// to survive restarts
+func (r *AppController) reportContentMetrics(ctx context.Context, app *v1beta1.OdhNimApp) error {
+	if app.Status.ConfigMapRef == nil || app.Status.LastContentUpdateTime == nil {
+		return nil
+	}
+
+	cm := &corev1.ConfigMap{}
+	cmKey := client.ObjectKey{Name: app.Status.ConfigMapRef.Name, Namespace: app.Namespace}
+	if err := r.Get(ctx, cmKey, cm); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	images := map[string]bool{}
+	for _, encoded := range cm.Data {
+		model := ngc.Model{}
+		if err := json.Unmarshal([]byte(encoded), &model); err == nil && model.Image != "" {
+			images[model.Image] = true
+		}
+	}
+
+	lastUpdate := app.Status.LastContentUpdateTime
+	metrics.SetContent(app.Namespace, app.Name, len(cm.Data), len(images),
+		lastUpdate.Time, nextAppRun(ctx, app, lastUpdate))
+	return nil
+}
+
+// reconcileServingTemplate is used for creating or patching the NIM serving Template owned by the OdhNimApp, manual
+// modifications of the Template are reverted, and the Template is re-rendered when the operator version changes.
+// OdhNimApp.Status.TemplateRef is set if empty.
+func (r *AppController) reconcileServingTemplate(ctx context.Context, app *v1beta1.OdhNimApp) error {
+	logger := log.FromContext(ctx)
+
+	template := &templatev1.Template{ObjectMeta: metav1.ObjectMeta{Name: Name_ServingTemplate, Namespace: app.Namespace}}
+	if app.Status.TemplateRef != nil && app.Status.TemplateRef.Name != "" {
+		template.Name = app.Status.TemplateRef.Name
+	}
+
+	operatorVersion := version.Get().GitVersion
+	var renderedVersion string
+	result, err := controllerutil.CreateOrPatch(ctx, r.Client, template, func() error {
+		renderedVersion = template.Annotations[Annotation_OperatorVersion]
+		if err := renderServingTemplate(template); err != nil {
+			return err
+		}
+		metav1.SetMetaDataAnnotation(&template.ObjectMeta, Annotation_OperatorVersion, operatorVersion)
+		return controllerutil.SetControllerReference(app, template, r.Scheme)
+	})
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case result == controllerutil.OperationResultCreated:
+		r.Recorder.Eventf(app, corev1.EventTypeNormal, Reason_ServingTemplateRendered,
+			"rendered serving template %s for operator version %s", template.Name, operatorVersion)
+	case result == controllerutil.OperationResultUpdated && renderedVersion != operatorVersion:
+		logger.Info("re-rendered serving template", "from", renderedVersion, "to", operatorVersion)
+		r.Recorder.Eventf(app, corev1.EventTypeNormal, Reason_ServingTemplateRendered,
+			"re-rendered serving template %s for operator version %s", template.Name, operatorVersion)
+	case result == controllerutil.OperationResultUpdated:
+		logger.Info("reverted serving template modifications")
+		r.Recorder.Eventf(app, corev1.EventTypeNormal, Reason_ServingTemplateReverted,
+			"reverted modifications of serving template %s", template.Name)
+	}
+
+	if app.Status.TemplateRef == nil || app.Status.TemplateRef.Name == "" {
+		if err = patchStatus(ctx, r.Client, app, func() {
+			app.Status.TemplateRef = &corev1.ObjectReference{Name: template.Name, Namespace: template.Namespace}
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// reconcileNgcSecrets is used for creating or patching the NGC pull Secret and API key Secret owned by the OdhNimApp,
+// both are derived from the API key referenced by the OdhNimApp. Keys are only swapped after they were validated, the
+// hash of the validated key is recorded in OdhNimApp.Status.ApiKeyHash, other keys are ignored and the last known good
+// key is kept. The NIM InferenceServices are restarted after a swap.
+func (r *AppController) reconcileNgcSecrets(ctx context.Context, app *v1beta1.OdhNimApp) error {
+	logger := log.FromContext(ctx)
+
+	apiKey, err := r.getApiKey(ctx, app)
+	if err != nil {
+		return err
+	}
+	apiKeyHash := hashApiKey(apiKey)
+	if apiKeyHash != app.Status.ApiKeyHash {
+		logger.Info("API key modified since the last validation, keeping the last known good API key")
+		return nil
+	}
+
+	pullSecret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: Name_PullSecret, Namespace: app.Namespace}}
+	result, err := controllerutil.CreateOrPatch(ctx, r.Client, pullSecret, func() error {
+		if err := renderPullSecret(pullSecret, apiKey); err != nil {
+			return err
+		}
+		// claim copies propagated from another OdhNimApp before this one was created, so it stops propagating them
+		delete(pullSecret.Labels, Label_PropagatedFrom)
+		return controllerutil.SetControllerReference(app, pullSecret, r.Scheme)
+	})
+	if err != nil {
+		return err
+	}
+	if result != controllerutil.OperationResultNone {
+		logger.Info(fmt.Sprintf("%s pull secret", result), "name", pullSecret.Name)
+	}
+
+	apiKeySecret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: Name_ApiKeySecret, Namespace: app.Namespace}}
+	result, err = controllerutil.CreateOrPatch(ctx, r.Client, apiKeySecret, func() error {
+		renderApiKeySecret(apiKeySecret, apiKey)
+		delete(apiKeySecret.Labels, Label_PropagatedFrom)
+		return controllerutil.SetControllerReference(app, apiKeySecret, r.Scheme)
+	})
+	if err != nil {
+		return err
+	}
+	if result != controllerutil.OperationResultNone {
+		logger.Info(fmt.Sprintf("%s API key secret", result), "name", apiKeySecret.Name)
+	}
+
+	switch result {
+	case controllerutil.OperationResultCreated:
+		r.Recorder.Event(app, corev1.EventTypeNormal, Reason_NgcSecretsCreated,
+			"created the NGC secrets from the validated API key")
+	case controllerutil.OperationResultUpdated:
+		r.Recorder.Event(app, corev1.EventTypeNormal, Reason_ApiKeySwapped,
+			"swapped the NGC secrets to the validated API key, restarting the NIM InferenceServices")
+		return restartInferenceServices(ctx, r.Client, app.Namespace, apiKeyHash)
+	}
+	return nil
+}
+
+// reconcileNimPvc is used for creating or expanding the NIM PVC owned by the OdhNimApp, and reporting whether the PVC
+// is bound in the OdhNimApp status, expansions rejected by the cluster are reported in the condition message
+func (r *AppController) reconcileNimPvc(ctx context.Context, app *v1beta1.OdhNimApp) error {
+	logger := log.FromContext(ctx)
+
+	pvc := &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: Name_NimPvc, Namespace: app.Namespace}}
+	var shrinkRequested bool
+	result, err := controllerutil.CreateOrPatch(ctx, r.Client, pvc, func() error {
+		shrinkRequested = renderNimPvc(pvc, app.Spec.Storage)
+		// claim the copy propagated from another OdhNimApp before this one was created, keeping the cached models
+		delete(pvc.Labels, Label_PropagatedFrom)
+		return controllerutil.SetControllerReference(app, pvc, r.Scheme)
+	})
+	var expansionErr error
+	if err != nil {
+		// expansions are rejected by the cluster if the storage class doesn't allow them, report and don't retry
+		if pvc.CreationTimestamp.IsZero() || (!k8serrors.IsForbidden(err) && !k8serrors.IsInvalid(err)) {
+			return err
+		}
+		logger.Info("NIM PVC expansion rejected", "reason", err.Error())
+		expansionErr = err
+		if err = r.Get(ctx, client.ObjectKeyFromObject(pvc), pvc); err != nil {
+			return err
+		}
+	}
+	if result != controllerutil.OperationResultNone {
+		logger.Info(fmt.Sprintf("%s NIM PVC", result), "name", pvc.Name)
+	}
+	if shrinkRequested {
+		logger.Info("ignoring NIM PVC size smaller than the current size", "name", pvc.Name)
+	}
+
+	condition := metav1.Condition{
+		Type:    Condition_NimPvcBound,
+		Status:  metav1.ConditionTrue,
+		Reason:  Reason_NimPvcBound,
+		Message: "NIM PVC bound",
+	}
+	switch pvc.Status.Phase {
+	case corev1.ClaimBound:
+		if capacity, found := pvc.Status.Capacity[corev1.ResourceStorage]; found {
+			condition.Message = fmt.Sprintf("NIM PVC bound with %s capacity", capacity.String())
+		}
+	case corev1.ClaimLost:
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = Reason_NimPvcLost
+		condition.Message = "NIM PVC lost its volume"
+	default:
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = Reason_NimPvcPending
+		condition.Message = "NIM PVC pending binding"
+	}
+	if expansionErr != nil {
+		condition.Message = fmt.Sprintf("%s, expansion rejected: %s", condition.Message, expansionErr.Error())
+	}
+
+	if isConditionCurrent(app, condition) {
+		return nil
+	}
+	recordConditionEvent(r.Recorder, app, condition)
+	return patchCondition(ctx, r.Client, app, condition)
+}
+
+// reconcileWarmups is used for running a model cache warm-up Job per model listed in OdhNimApp.Spec.Warmup, for the
+// model latest version in the content catalog, and reporting their progress in OdhNimApp.Status.Warmups. Catalog
+// version changes re-run the warm-up, Jobs of previous versions or unlisted models are deleted. Models with no version
+// in the content catalog are reported as skipped.
+func (r *AppController) reconcileWarmups(ctx context.Context, app *v1beta1.OdhNimApp) error {
+	logger := log.FromContext(ctx)
+
+	cm := &corev1.ConfigMap{}
+	if len(app.Spec.Warmup.Models) > 0 && app.Status.ConfigMapRef != nil {
+		cmKey := client.ObjectKey{Name: app.Status.ConfigMapRef.Name, Namespace: app.Namespace}
+		if err := r.Get(ctx, cmKey, cm); err != nil && !k8serrors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	keep := map[string]bool{}
+	var warmups []v1beta1.OdhNimAppStatusWarmup
+	for _, name := range app.Spec.Warmup.Models {
+		warmup := v1beta1.OdhNimAppStatusWarmup{Model: name, Phase: v1beta1.WarmupPhaseFailed}
+
+		encoded, found := cm.Data[name]
+		if !found {
+			warmup.Message = "model not found in the content catalog"
+			warmups = append(warmups, warmup)
+			continue
+		}
+		model := ngc.Model{}
+		if err := json.Unmarshal([]byte(encoded), &model); err != nil {
+			warmup.Message = fmt.Sprintf("failed decoding the content catalog model: %s", err.Error())
+			warmups = append(warmups, warmup)
+			continue
+		}
+
+		// models with no tags have no image to pull
+		if model.LatestTag == "" {
+			warmup.Phase = v1beta1.WarmupPhaseSkipped
+			warmup.Message = "the content catalog model has no version to warm up"
+			warmups = append(warmups, warmup)
+			continue
+		}
+
+		warmup.Version = model.LatestTag
+		job := newWarmupJob(app.Namespace, model, model.LatestTag)
+		if keep[job.Name] {
+			continue
+		}
+		keep[job.Name] = true
+
+		if err := r.Get(ctx, client.ObjectKeyFromObject(job), job); err != nil {
+			if !k8serrors.IsNotFound(err) {
+				return err
+			}
+			if err = controllerutil.SetControllerReference(app, job, r.Scheme); err != nil {
+				return err
+			}
+			if err = r.Create(ctx, job); err != nil {
+				return err
+			}
+			logger.Info("created model cache warm-up job", "name", job.Name, "model", name, "version", warmup.Version)
+			r.Recorder.Eventf(app, corev1.EventTypeNormal, Reason_WarmupStarted,
+				"started warming up the model cache for %s version %s", name, warmup.Version)
+		}
+
+		warmup.JobRef = &corev1.ObjectReference{Name: job.Name, Namespace: job.Namespace}
+		warmup.Phase, warmup.CompletionTime, warmup.Message = warmupJobStatus(job)
+		warmups = append(warmups, warmup)
+	}
+
+	if err := deleteWarmupJobs(ctx, r.Client, app, keep); err != nil {
+		return err
+	}
+
+	if equality.Semantic.DeepEqual(warmups, app.Status.Warmups) {
+		return nil
+	}
+	return patchStatus(ctx, r.Client, app, func() {
+		app.Status.Warmups = warmups
+	})
+}
+
+// deleteWarmupJobs is used for deleting the model cache warm-up Jobs controlled by the OdhNimApp, except for the ones
+// named in keep, a nil keep deletes all of them
+func deleteWarmupJobs(ctx context.Context, c client.Client, app *v1beta1.OdhNimApp, keep map[string]bool) error {
+	logger := log.FromContext(ctx)
+
+	jobs := &batchv1.JobList{}
+	selector := client.MatchingLabels{Label_Warmup: "true"}
+	if err := c.List(ctx, jobs, client.InNamespace(app.Namespace), selector); err != nil {
+		return err
+	}
+
+	for i := range jobs.Items {
+		job := &jobs.Items[i]
+		if keep[job.Name] || !metav1.IsControlledBy(job, app) {
+			continue
+		}
+		// jobs orphan their pods by default
+		if err := c.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil &&
+			!k8serrors.IsNotFound(err) {
+			return err
+		}
+		logger.Info("deleted model cache warm-up job", "name", job.Name)
+	}
+	return nil
+}
+
+// deleteGeneratedResources is used for deleting the resources generated for the OdhNimApp, the OdhNimApp itself is kept
+func deleteGeneratedResources(ctx context.Context, c client.Client, app *v1beta1.OdhNimApp) error {
+	cmName := Name_ContentConfigMap
+	if app.Status.ConfigMapRef != nil && app.Status.ConfigMapRef.Name != "" {
+		cmName = app.Status.ConfigMapRef.Name
+	}
+	templateName := Name_ServingTemplate
+	if app.Status.TemplateRef != nil && app.Status.TemplateRef.Name != "" {
+		templateName = app.Status.TemplateRef.Name
+	}
+
+	if err := deleteWarmupJobs(ctx, c, app, nil); err != nil {
+		return err
+	}
+
+	return deleteControlled(ctx, c, app,
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: cmName, Namespace: app.Namespace}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: Name_ContentHistory, Namespace: app.Namespace}},
+		&templatev1.Template{ObjectMeta: metav1.ObjectMeta{Name: templateName, Namespace: app.Namespace}},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: Name_PullSecret, Namespace: app.Namespace}},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: Name_ApiKeySecret, Namespace: app.Namespace}},
+		&corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: Name_NimPvc, Namespace: app.Namespace}},
+	)
+}
+
+// getApiKey is used for fetching the API key from the Secret referenced by the OdhNimApp, the Secret must be labeled
+func (r *AppController) getApiKey(ctx context.Context, app *v1beta1.OdhNimApp) (string, error) {
+	ref := app.Spec.ApiKey.SecretRef
+	if ref == nil || ref.Name == "" {
+		return "", errMissingApiKey("no API key secret referenced")
+	}
+
+	key := client.ObjectKey{Name: ref.Name, Namespace: ref.Namespace}
+	if key.Namespace == "" {
+		key.Namespace = app.Namespace
+	}
+
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, key, secret); err != nil {
+		return "", err
+	}
+
+	if !hasNimAppLabel(secret) {
+		return "", errMissingApiKey(fmt.Sprintf("secret %s is not labeled %s=true", key, Label_NimApp))
+	}
+
+	apiKey, found := secret.Data[Key_ApiKey]
+	if !found || len(apiKey) == 0 {
+		return "", errMissingApiKey(fmt.Sprintf("secret %s has no %s key", key, Key_ApiKey))
+	}
+	return string(apiKey), nil
+}
+
+// errMissingApiKey is used for reporting a missing API key
+type errMissingApiKey string
+
+func (e errMissingApiKey) Error() string {
+	return string(e)
+}
+
+// mapImageMirror is used for mapping cluster mirror object events to requests for all the OdhNimApps, so the NIM
+// ServingRuntimes are rewritten once the mirrors are modified
+func (r *AppController) mapImageMirror(_ client.Object) []reconcile.Request {
+	apps := &v1beta1.OdhNimAppList{}
+	if err := r.List(context.Background(), apps); err != nil {
+		log.Log.WithName("app-controller").Error(err, "failed listing OdhNimApps")
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, app := range apps.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&app)})
+	}
+	return requests
+}
+
+// isApiKeyAccepted is used for checking if the validation condition accepts the API key, either validated, or set with
+// the validation skipped by the operator
+func isApiKeyAccepted(condition metav1.Condition) bool {
+	return condition.Status == metav1.ConditionTrue || condition.Reason == Reason_ApiKeyValidationSkipped
+}
+
+// isMissingApiKey is used for checking if an error reports a missing API key
+func isMissingApiKey(err error) bool {
+	_, ok := err.(errMissingApiKey)
+	return ok
 }
 
 // init is used for registering the odh-nim-app controller for loading
 func init() {
 	controllerSetups = append(controllerSetups, func(opts ControllerOptions) error {
-		return (&SecretController{
+		return (&AppController{
 			opts.Manager.GetClient(),
 			opts.Manager.GetScheme(),
+			opts.NgcClient,
+			opts.Manager.GetEventRecorderFor("odh-nim-app-controller"),
+			opts.OfflineCatalog,
+			opts.SkipApiKeyValidation,
 		}).SetupWithManager(opts.Manager)
 	})
 }
@@ -0,0 +1,1047 @@
+// Copyright (c) 2024 Red Hat, Inc.
+
+package controllers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/opendatahub-io/odh-nim-operator/api/v1beta1"
+	"github.com/opendatahub-io/odh-nim-operator/pkg/ngc"
+	"github.com/opendatahub-io/odh-nim-operator/pkg/version"
+	templatev1 "github.com/openshift/api/template/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/record"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"strings"
+	"time"
+)
+
+var _ = Describe("AppController", func() {
+	var reconciler *AppController
+	var recorder *record.FakeRecorder
+	var namespace *corev1.Namespace
+	var app *v1beta1.OdhNimApp
+	var request ctrl.Request
+
+	BeforeEach(func(ctx SpecContext) {
+		recorder = record.NewFakeRecorder(100)
+		reconciler = &AppController{testClient, testClient.Scheme(), testNgcClient, recorder, "", false}
+
+		namespace = &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{GenerateName: "app-controller-"}}
+		Expect(testClient.Create(ctx, namespace)).To(Succeed())
+
+		app = newTestApp(namespace.Name)
+		Expect(testClient.Create(ctx, app)).To(Succeed())
+		request = ctrl.Request{NamespacedName: client.ObjectKeyFromObject(app)}
+	})
+
+	When("the API key secret does not exist", func() {
+		It("should report a failed validation and reset the validation request", func(ctx SpecContext) {
+			_, err := reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(testClient.Get(ctx, request.NamespacedName, app)).To(Succeed())
+			Expect(controllerutil.ContainsFinalizer(app, Finalizer_NimAppCleanup)).To(BeTrue())
+			Expect(app.Spec.ApiKey.Validate).To(BeFalse())
+
+			condition := meta.FindStatusCondition(app.Status.Conditions, Condition_ApiKeyValidated)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal(Reason_ApiKeyValidationFailed))
+			Expect(meta.FindStatusCondition(app.Status.Conditions, Condition_ContentUpdated)).To(BeNil())
+		})
+	})
+
+	When("the API key is rejected by NGC", func() {
+		BeforeEach(func(ctx SpecContext) {
+			Expect(testClient.Create(ctx, newTestApiKeySecret(namespace.Name, "my-invalid-api-key"))).To(Succeed())
+		})
+
+		It("should report an invalid API key and requeue for the next scheduled validation", func(ctx SpecContext) {
+			result, err := reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(testClient.Get(ctx, request.NamespacedName, app)).To(Succeed())
+			Expect(app.Spec.ApiKey.Validate).To(BeFalse())
+
+			condition := meta.FindStatusCondition(app.Status.Conditions, Condition_ApiKeyValidated)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal(Reason_ApiKeyInvalid))
+			Expect(drainEvents(recorder)).To(ContainElement(HavePrefix("Warning " + Reason_ApiKeyInvalid)))
+			Expect(result.RequeueAfter).To(BeNumerically("~",
+				defaultScheduleInterval+scheduleJitter(app, defaultScheduleInterval), time.Minute))
+		})
+	})
+
+	When("NGC is unreachable", func() {
+		BeforeEach(func(ctx SpecContext) {
+			Expect(testClient.Create(ctx, newTestApiKeySecret(namespace.Name, testValidApiKey))).To(Succeed())
+
+			unreachableClient, err := ngc.NewClient(ngc.ClientOptions{AuthUrl: "http://127.0.0.1:1"})
+			Expect(err).NotTo(HaveOccurred())
+			reconciler.NgcClient = unreachableClient
+		})
+
+		It("should keep the validation request and requeue", func(ctx SpecContext) {
+			result, err := reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(ngcRetryInterval))
+
+			Expect(testClient.Get(ctx, request.NamespacedName, app)).To(Succeed())
+			Expect(app.Spec.ApiKey.Validate).To(BeTrue())
+
+			condition := meta.FindStatusCondition(app.Status.Conditions, Condition_ApiKeyValidated)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionUnknown))
+			Expect(condition.Reason).To(Equal(Reason_NgcUnreachable))
+		})
+	})
+
+	When("NGC rate limits requests", func() {
+		BeforeEach(func(ctx SpecContext) {
+			Expect(testClient.Create(ctx, newTestApiKeySecret(namespace.Name, testValidApiKey))).To(Succeed())
+
+			throttling := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Retry-After", "120")
+				w.WriteHeader(http.StatusTooManyRequests)
+			}))
+			DeferCleanup(throttling.Close)
+			throttledClient, err := ngc.NewClient(ngc.ClientOptions{AuthUrl: throttling.URL})
+			Expect(err).NotTo(HaveOccurred())
+			reconciler.NgcClient = throttledClient
+		})
+
+		It("should keep the validation request and requeue once NGC accepts requests", func(ctx SpecContext) {
+			result, err := reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(BeNumerically(">=", 2*time.Minute-time.Second))
+			Expect(result.RequeueAfter).To(BeNumerically("<=", 2*time.Minute+2*time.Minute/5))
+
+			Expect(testClient.Get(ctx, request.NamespacedName, app)).To(Succeed())
+			Expect(app.Spec.ApiKey.Validate).To(BeTrue())
+			Expect(app.Status.LastValidationTime).To(BeNil())
+
+			condition := meta.FindStatusCondition(app.Status.Conditions, Condition_ApiKeyValidated)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionUnknown))
+			Expect(condition.Reason).To(Equal(Reason_NgcRateLimited))
+		})
+	})
+
+	When("the cluster is disconnected from NGC", func() {
+		offlineModels := []string{"llama3-8b-instruct", "mistral-7b-instruct"}
+
+		BeforeEach(func(ctx SpecContext) {
+			Expect(testClient.Create(ctx, newTestApiKeySecret(namespace.Name, testValidApiKey))).To(Succeed())
+
+			unreachableClient, err := ngc.NewClient(ngc.ClientOptions{
+				AuthUrl: "http://127.0.0.1:1",
+				ApiUrl:  "http://127.0.0.1:1",
+			})
+			Expect(err).NotTo(HaveOccurred())
+			reconciler.NgcClient = unreachableClient
+			reconciler.SkipApiKeyValidation = true
+		})
+
+		expectOfflineContent := func(ctx SpecContext) {
+			_, err := reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(testClient.Get(ctx, request.NamespacedName, app)).To(Succeed())
+			condition := meta.FindStatusCondition(app.Status.Conditions, Condition_ApiKeyValidated)
+			Expect(condition.Status).To(Equal(metav1.ConditionUnknown))
+			Expect(condition.Reason).To(Equal(Reason_ApiKeyValidationSkipped))
+			Expect(meta.IsStatusConditionTrue(app.Status.Conditions, Condition_ContentUpdated)).To(BeTrue())
+
+			cm := &corev1.ConfigMap{}
+			cmKey := client.ObjectKey{Name: Name_ContentConfigMap, Namespace: namespace.Name}
+			Expect(testClient.Get(ctx, cmKey, cm)).To(Succeed())
+			Expect(cm.Data).To(HaveLen(len(offlineModels)))
+			for _, name := range offlineModels {
+				Expect(cm.Data).To(HaveKey(name))
+			}
+		}
+
+		It("should load the content from the offline catalog ConfigMap", func(ctx SpecContext) {
+			catalog := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "nim-catalog-bundle", Namespace: namespace.Name},
+				Data:       map[string]string{},
+			}
+			for _, name := range offlineModels {
+				catalog.Data[name] = fmt.Sprintf(`{"name":%q,"image":"registry.internal/nim/%s"}`, name, name)
+			}
+			Expect(testClient.Create(ctx, catalog)).To(Succeed())
+
+			patch := client.MergeFrom(app.DeepCopy())
+			app.Spec.Offline.CatalogRef = &corev1.ObjectReference{Name: catalog.Name}
+			Expect(testClient.Patch(ctx, app, patch)).To(Succeed())
+
+			expectOfflineContent(ctx)
+		})
+
+		It("should load the content from the offline catalog mounted in the operator pod", func(ctx SpecContext) {
+			var documents []string
+			for _, name := range offlineModels {
+				documents = append(documents, fmt.Sprintf(`{"name":%q,"image":"registry.internal/nim/%s"}`, name, name))
+			}
+			reconciler.OfflineCatalog = filepath.Join(GinkgoT().TempDir(), "catalog.json")
+			Expect(os.WriteFile(reconciler.OfflineCatalog,
+				[]byte("["+strings.Join(documents, ",")+"]"), 0644)).To(Succeed())
+
+			expectOfflineContent(ctx)
+		})
+
+		It("should report a failed content update for a missing offline catalog ConfigMap", func(ctx SpecContext) {
+			patch := client.MergeFrom(app.DeepCopy())
+			app.Spec.Offline.CatalogRef = &corev1.ObjectReference{Name: "missing-catalog"}
+			Expect(testClient.Patch(ctx, app, patch)).To(Succeed())
+
+			_, err := reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(testClient.Get(ctx, request.NamespacedName, app)).To(Succeed())
+			condition := meta.FindStatusCondition(app.Status.Conditions, Condition_ApiKeyValidated)
+			Expect(condition.Reason).To(Equal(Reason_ApiKeyValidationSkipped))
+			condition = meta.FindStatusCondition(app.Status.Conditions, Condition_ContentUpdated)
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal(Reason_ContentUpdateFailed))
+		})
+
+		It("should not report the content of a malformed offline catalog ConfigMap", func(ctx SpecContext) {
+			catalog := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "nim-catalog-bundle", Namespace: namespace.Name},
+				Data:       map[string]string{"private-model-name": "not json"},
+			}
+			Expect(testClient.Create(ctx, catalog)).To(Succeed())
+			patch := client.MergeFrom(app.DeepCopy())
+			app.Spec.Offline.CatalogRef = &corev1.ObjectReference{Name: catalog.Name}
+			Expect(testClient.Patch(ctx, app, patch)).To(Succeed())
+
+			_, err := reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(testClient.Get(ctx, request.NamespacedName, app)).To(Succeed())
+			condition := meta.FindStatusCondition(app.Status.Conditions, Condition_ContentUpdated)
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Message).To(ContainSubstring("is malformed"))
+			Expect(condition.Message).NotTo(ContainSubstring("private-model-name"))
+		})
+
+		It("should not read offline catalog ConfigMaps from other namespaces", func(ctx SpecContext) {
+			other := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{GenerateName: "offline-other-"}}
+			Expect(testClient.Create(ctx, other)).To(Succeed())
+			catalog := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "nim-catalog-bundle", Namespace: other.Name},
+				Data:       map[string]string{"llama3-8b-instruct": `{"name":"llama3-8b-instruct"}`},
+			}
+			Expect(testClient.Create(ctx, catalog)).To(Succeed())
+			patch := client.MergeFrom(app.DeepCopy())
+			app.Spec.Offline.CatalogRef = &corev1.ObjectReference{Name: catalog.Name, Namespace: other.Name}
+			Expect(testClient.Patch(ctx, app, patch)).To(Succeed())
+
+			_, err := reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(testClient.Get(ctx, request.NamespacedName, app)).To(Succeed())
+			condition := meta.FindStatusCondition(app.Status.Conditions, Condition_ContentUpdated)
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal(Reason_ContentUpdateFailed))
+		})
+	})
+
+	When("the API key is accepted by NGC", func() {
+		BeforeEach(func(ctx SpecContext) {
+			Expect(testClient.Create(ctx, newTestApiKeySecret(namespace.Name, testValidApiKey))).To(Succeed())
+		})
+
+		It("should report a successful validation and reconcile the content", func(ctx SpecContext) {
+			_, err := reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(testClient.Get(ctx, request.NamespacedName, app)).To(Succeed())
+			Expect(app.Spec.ApiKey.Validate).To(BeFalse())
+			Expect(app.Spec.Content.Update).To(BeFalse())
+			Expect(meta.IsStatusConditionTrue(app.Status.Conditions, Condition_ApiKeyValidated)).To(BeTrue())
+			Expect(meta.IsStatusConditionTrue(app.Status.Conditions, Condition_ContentUpdated)).To(BeTrue())
+			Expect(app.Status.ConfigMapRef).NotTo(BeNil())
+			Expect(app.Status.ConfigMapRef.Name).To(Equal(Name_ContentConfigMap))
+
+			cm := &corev1.ConfigMap{}
+			cmKey := client.ObjectKey{Name: Name_ContentConfigMap, Namespace: namespace.Name}
+			Expect(testClient.Get(ctx, cmKey, cm)).To(Succeed())
+			Expect(metav1.IsControlledBy(cm, app)).To(BeTrue())
+			Expect(cm.Annotations).To(HaveKeyWithValue(Annotation_ContentSchemaVersion, ngc.CatalogSchemaVersion))
+			Expect(cm.Data).To(HaveKey(testModelName))
+
+			model := &ngc.Model{}
+			Expect(json.Unmarshal([]byte(cm.Data[testModelName]), model)).To(Succeed())
+			Expect(model.Image).To(Equal("nvcr.io/nim/meta/" + testModelName))
+		})
+
+		It("should reconcile the serving template", func(ctx SpecContext) {
+			_, err := reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(testClient.Get(ctx, request.NamespacedName, app)).To(Succeed())
+			Expect(app.Status.TemplateRef).NotTo(BeNil())
+			Expect(app.Status.TemplateRef.Name).To(Equal(Name_ServingTemplate))
+
+			template := &templatev1.Template{}
+			templateKey := client.ObjectKey{Name: Name_ServingTemplate, Namespace: namespace.Name}
+			Expect(testClient.Get(ctx, templateKey, template)).To(Succeed())
+			Expect(metav1.IsControlledBy(template, app)).To(BeTrue())
+			Expect(template.Labels).To(HaveKeyWithValue(Label_OdhDashboard, "true"))
+			Expect(template.Annotations).To(HaveKeyWithValue(Annotation_OperatorVersion, version.Get().GitVersion))
+			Expect(template.Objects).To(HaveLen(1))
+
+			servingRuntime := &unstructured.Unstructured{}
+			Expect(json.Unmarshal(template.Objects[0].Raw, servingRuntime)).To(Succeed())
+			Expect(servingRuntime.GetKind()).To(Equal("ServingRuntime"))
+			Expect(servingRuntime.GetName()).To(Equal(Name_ServingRuntime))
+		})
+
+		It("should derive the NGC secrets from the API key", func(ctx SpecContext) {
+			_, err := reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(testClient.Get(ctx, request.NamespacedName, app)).To(Succeed())
+
+			pullSecret := &corev1.Secret{}
+			pullSecretKey := client.ObjectKey{Name: Name_PullSecret, Namespace: namespace.Name}
+			Expect(testClient.Get(ctx, pullSecretKey, pullSecret)).To(Succeed())
+			Expect(metav1.IsControlledBy(pullSecret, app)).To(BeTrue())
+			Expect(pullSecret.Type).To(Equal(corev1.SecretTypeDockerConfigJson))
+			Expect(pullSecret.Data[corev1.DockerConfigJsonKey]).To(MatchJSON(fmt.Sprintf(
+				`{"auths":{"nvcr.io":{"username":"$oauthtoken","password":%q,"auth":%q}}}`, testValidApiKey,
+				base64.StdEncoding.EncodeToString([]byte("$oauthtoken:"+testValidApiKey)))))
+
+			apiKeySecret := &corev1.Secret{}
+			apiKeySecretKey := client.ObjectKey{Name: Name_ApiKeySecret, Namespace: namespace.Name}
+			Expect(testClient.Get(ctx, apiKeySecretKey, apiKeySecret)).To(Succeed())
+			Expect(metav1.IsControlledBy(apiKeySecret, app)).To(BeTrue())
+			Expect(apiKeySecret.Type).To(Equal(corev1.SecretTypeOpaque))
+			Expect(apiKeySecret.Data).To(HaveKeyWithValue(Key_NgcApiKey, []byte(testValidApiKey)))
+
+			By("reverting modifications of the derived secrets")
+			patch := client.MergeFrom(apiKeySecret.DeepCopy())
+			apiKeySecret.Data[Key_NgcApiKey] = []byte("modified")
+			Expect(testClient.Patch(ctx, apiKeySecret, patch)).To(Succeed())
+
+			_, err = reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(testClient.Get(ctx, apiKeySecretKey, apiKeySecret)).To(Succeed())
+			Expect(apiKeySecret.Data).To(HaveKeyWithValue(Key_NgcApiKey, []byte(testValidApiKey)))
+		})
+
+		It("should revert modifications of the serving template", func(ctx SpecContext) {
+			_, err := reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+
+			template := &templatev1.Template{}
+			templateKey := client.ObjectKey{Name: Name_ServingTemplate, Namespace: namespace.Name}
+			Expect(testClient.Get(ctx, templateKey, template)).To(Succeed())
+			rendered := template.DeepCopy()
+
+			patch := client.MergeFrom(template.DeepCopy())
+			template.Objects[0].Raw = []byte(`{"apiVersion":"serving.kserve.io/v1alpha1","kind":"ServingRuntime"}`)
+			template.Annotations[Annotation_OperatorVersion] = "v0.0.0"
+			Expect(testClient.Patch(ctx, template, patch)).To(Succeed())
+
+			_, err = reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(testClient.Get(ctx, templateKey, template)).To(Succeed())
+			Expect(template.Annotations).To(HaveKeyWithValue(Annotation_OperatorVersion, version.Get().GitVersion))
+			Expect(template.Objects).To(HaveLen(1))
+			Expect(template.Objects[0].Raw).To(MatchJSON(rendered.Objects[0].Raw))
+		})
+
+		It("should report the catalog diff between content updates", func(ctx SpecContext) {
+			_, err := reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(testClient.Get(ctx, request.NamespacedName, app)).To(Succeed())
+			Expect(app.Status.LastCatalogDiff).To(BeNil())
+			drainEvents(recorder)
+
+			// stand in for a previous catalog holding another model and an older version of the served model
+			cm := &corev1.ConfigMap{}
+			cmKey := client.ObjectKey{Name: Name_ContentConfigMap, Namespace: namespace.Name}
+			Expect(testClient.Get(ctx, cmKey, cm)).To(Succeed())
+			previous, err := json.Marshal(ngc.Model{Name: testModelName, Tags: []string{"0.9.0"}, LatestTag: "0.9.0"})
+			Expect(err).NotTo(HaveOccurred())
+			patch := client.MergeFrom(cm.DeepCopy())
+			cm.Data = map[string]string{testModelName: string(previous), "removed-model": "{}"}
+			Expect(testClient.Patch(ctx, cm, patch)).To(Succeed())
+
+			patch = client.MergeFrom(app.DeepCopy())
+			metav1.SetMetaDataAnnotation(&app.ObjectMeta, Annotation_RefreshRequestedAt, "2024-06-01T11:00:00Z")
+			Expect(testClient.Patch(ctx, app, patch)).To(Succeed())
+			_, err = reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(testClient.Get(ctx, request.NamespacedName, app)).To(Succeed())
+			diff := app.Status.LastCatalogDiff
+			Expect(diff).NotTo(BeNil())
+			Expect(diff.AddedModels).To(BeEmpty())
+			Expect(diff.RemovedModels).To(ConsistOf("removed-model"))
+			Expect(diff.UpdatedModels).To(ConsistOf(testModelName))
+			Expect(diff.Summary).To(Equal("0 models added, 1 removed, 1 updated"))
+			Expect(diff.HistoryRef).NotTo(BeNil())
+			Expect(drainEvents(recorder)).To(ConsistOf(HavePrefix("Normal " + Reason_ContentChanged)))
+
+			history := &corev1.ConfigMap{}
+			historyKey := client.ObjectKey{Name: diff.HistoryRef.Name, Namespace: namespace.Name}
+			Expect(testClient.Get(ctx, historyKey, history)).To(Succeed())
+			Expect(metav1.IsControlledBy(history, app)).To(BeTrue())
+			var diffs []catalogDiff
+			Expect(json.Unmarshal([]byte(history.Data[Key_ContentHistory]), &diffs)).To(Succeed())
+			Expect(diffs).To(HaveLen(1))
+			Expect(diffs[0].Updated).To(ConsistOf(catalogModelDiff{
+				Name:           testModelName,
+				AddedTags:      []string{"1.0.0"},
+				RemovedTags:    []string{"0.9.0"},
+				PreviousLatest: "0.9.0",
+				Latest:         "1.0.0",
+			}))
+		})
+
+		It("should keep the last known good content and hold off retries after repeated failures",
+			func(ctx SpecContext) {
+				_, err := reconciler.Reconcile(ctx, request)
+				Expect(err).NotTo(HaveOccurred())
+
+				// stand in for a previous catalog holding more models than NGC serves now
+				cm := &corev1.ConfigMap{}
+				cmKey := client.ObjectKey{Name: Name_ContentConfigMap, Namespace: namespace.Name}
+				Expect(testClient.Get(ctx, cmKey, cm)).To(Succeed())
+				patch := client.MergeFrom(cm.DeepCopy())
+				cm.Data["other-model-a"] = "{}"
+				cm.Data["other-model-b"] = "{}"
+				Expect(testClient.Patch(ctx, cm, patch)).To(Succeed())
+
+				// validations also update the content, the retry delay of failures below the threshold is expired
+				requestValidation := func() {
+					Expect(testClient.Get(ctx, request.NamespacedName, app)).To(Succeed())
+					if app.Status.ConsecutiveContentFailures < contentCircuitThreshold && app.Status.ContentRetryTime != nil {
+						app.Status.ContentRetryTime = &metav1.Time{Time: time.Now().Add(-time.Second)}
+						Expect(testClient.Status().Update(ctx, app)).To(Succeed())
+					}
+					patch := client.MergeFrom(app.DeepCopy())
+					app.Spec.ApiKey.Validate = true
+					Expect(testClient.Patch(ctx, app, patch)).To(Succeed())
+					_, err := reconciler.Reconcile(ctx, request)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(testClient.Get(ctx, request.NamespacedName, app)).To(Succeed())
+				}
+				for i := 0; i < contentCircuitThreshold; i++ {
+					requestValidation()
+				}
+
+				condition := meta.FindStatusCondition(app.Status.Conditions, Condition_ContentUpdated)
+				Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+				Expect(condition.Reason).To(Equal(Reason_ContentDegraded))
+				Expect(condition.Message).To(ContainSubstring("serving the last known good content"))
+				Expect(app.Status.ConsecutiveContentFailures).To(BeEquivalentTo(contentCircuitThreshold))
+				Expect(app.Status.ContentRetryTime).NotTo(BeNil())
+				Expect(testClient.Get(ctx, cmKey, cm)).To(Succeed())
+				Expect(cm.Data).To(HaveLen(3))
+
+				By("holding off content updates while the circuit breaker is open")
+				requestValidation()
+				Expect(app.Status.ConsecutiveContentFailures).To(BeEquivalentTo(contentCircuitThreshold))
+
+				By("accepting the catalog when a refresh is requested")
+				patch = client.MergeFrom(app.DeepCopy())
+				metav1.SetMetaDataAnnotation(&app.ObjectMeta, Annotation_RefreshRequestedAt, "2024-06-01T11:00:00Z")
+				Expect(testClient.Patch(ctx, app, patch)).To(Succeed())
+				_, err = reconciler.Reconcile(ctx, request)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(testClient.Get(ctx, request.NamespacedName, app)).To(Succeed())
+				Expect(meta.IsStatusConditionTrue(app.Status.Conditions, Condition_ContentUpdated)).To(BeTrue())
+				Expect(app.Status.ConsecutiveContentFailures).To(BeZero())
+				Expect(app.Status.ContentRetryTime).To(BeNil())
+				Expect(testClient.Get(ctx, cmKey, cm)).To(Succeed())
+				Expect(cm.Data).To(HaveLen(1))
+			})
+
+		It("should hold off content updates rate limited by NGC without opening the circuit breaker",
+			func(ctx SpecContext) {
+				_, err := reconciler.Reconcile(ctx, request)
+				Expect(err).NotTo(HaveOccurred())
+
+				// NGC accepts the API key but throttles the catalog requests
+				handler := newTestNgcHandler()
+				throttling := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if strings.HasPrefix(r.URL.Path, "/v2/") {
+						w.Header().Set("Retry-After", "600")
+						w.WriteHeader(http.StatusTooManyRequests)
+						return
+					}
+					handler.ServeHTTP(w, r)
+				}))
+				DeferCleanup(throttling.Close)
+				throttledClient, err := ngc.NewClient(ngc.ClientOptions{AuthUrl: throttling.URL, ApiUrl: throttling.URL})
+				Expect(err).NotTo(HaveOccurred())
+				reconciler.NgcClient = throttledClient
+
+				Expect(testClient.Get(ctx, request.NamespacedName, app)).To(Succeed())
+				patch := client.MergeFrom(app.DeepCopy())
+				app.Spec.ApiKey.Validate = true
+				Expect(testClient.Patch(ctx, app, patch)).To(Succeed())
+				_, err = reconciler.Reconcile(ctx, request)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(testClient.Get(ctx, request.NamespacedName, app)).To(Succeed())
+				condition := meta.FindStatusCondition(app.Status.Conditions, Condition_ContentUpdated)
+				Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+				Expect(condition.Reason).To(Equal(Reason_ContentDegraded))
+				Expect(condition.Message).To(ContainSubstring(ngc.ErrRateLimited.Error()))
+				Expect(app.Status.ConsecutiveContentFailures).To(BeZero())
+				Expect(app.Status.ContentRetryTime).NotTo(BeNil())
+				Expect(time.Until(app.Status.ContentRetryTime.Time)).
+					To(BeNumerically("~", 10*time.Minute+time.Minute, time.Minute+time.Second))
+			})
+
+		It("should rewrite the images to the registry mirrors", func(ctx SpecContext) {
+			mirrorSet := &unstructured.Unstructured{}
+			mirrorSet.SetAPIVersion("config.openshift.io/v1")
+			mirrorSet.SetKind("ImageDigestMirrorSet")
+			mirrorSet.SetGenerateName("nim-mirrors-")
+			Expect(unstructured.SetNestedSlice(mirrorSet.Object, []any{
+				map[string]any{"source": "nvcr.io/nim", "mirrors": []any{"registry.internal/nim"}},
+			}, "spec", "imageDigestMirrors")).To(Succeed())
+			Expect(testClient.Create(ctx, mirrorSet)).To(Succeed())
+			DeferCleanup(func(ctx SpecContext) {
+				Expect(client.IgnoreNotFound(testClient.Delete(ctx, mirrorSet))).To(Succeed())
+			})
+
+			runtime := newServingRuntime()
+			runtime.SetNamespace(namespace.Name)
+			containers, _, _ := unstructured.NestedSlice(runtime.Object, "spec", "containers")
+			containers[0].(map[string]any)["image"] = "nvcr.io/nim/meta/" + testModelName + ":1.0.0"
+			Expect(unstructured.SetNestedSlice(runtime.Object, containers, "spec", "containers")).To(Succeed())
+			Expect(testClient.Create(ctx, runtime)).To(Succeed())
+
+			_, err := reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+
+			cm := &corev1.ConfigMap{}
+			cmKey := client.ObjectKey{Name: Name_ContentConfigMap, Namespace: namespace.Name}
+			Expect(testClient.Get(ctx, cmKey, cm)).To(Succeed())
+			model := &ngc.Model{}
+			Expect(json.Unmarshal([]byte(cm.Data[testModelName]), model)).To(Succeed())
+			Expect(model.Image).To(Equal("registry.internal/nim/meta/" + testModelName))
+			Expect(model.SourceImage).To(Equal("nvcr.io/nim/meta/" + testModelName))
+
+			Expect(testClient.Get(ctx, request.NamespacedName, app)).To(Succeed())
+			Expect(app.Status.ImageMirrors).To(Equal([]v1beta1.OdhNimAppStatusImageMirror{{
+				Source: "nvcr.io/nim",
+				Mirror: "registry.internal/nim",
+				Origin: "ImageDigestMirrorSet/" + mirrorSet.GetName(),
+				Images: 1,
+			}}))
+
+			Expect(testClient.Get(ctx, client.ObjectKeyFromObject(runtime), runtime)).To(Succeed())
+			containers, _, _ = unstructured.NestedSlice(runtime.Object, "spec", "containers")
+			Expect(containers[0]).To(HaveKeyWithValue("image", "registry.internal/nim/meta/"+testModelName+":1.0.0"))
+			Expect(runtime.GetAnnotations()).To(HaveKey(Annotation_SourceImages))
+
+			By("preferring the mirrors of the OdhNimApp")
+			patch := client.MergeFrom(app.DeepCopy())
+			app.Spec.Mirrors = map[string]string{"nvcr.io/nim/meta": "mirror.internal/meta"}
+			metav1.SetMetaDataAnnotation(&app.ObjectMeta, Annotation_RefreshRequestedAt, "2024-06-01T11:00:00Z")
+			Expect(testClient.Patch(ctx, app, patch)).To(Succeed())
+			_, err = reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(testClient.Get(ctx, cmKey, cm)).To(Succeed())
+			Expect(json.Unmarshal([]byte(cm.Data[testModelName]), model)).To(Succeed())
+			Expect(model.Image).To(Equal("mirror.internal/meta/" + testModelName))
+			Expect(testClient.Get(ctx, request.NamespacedName, app)).To(Succeed())
+			Expect(app.Status.ImageMirrors).To(HaveLen(1))
+			Expect(app.Status.ImageMirrors[0].Origin).To(Equal(mirrorOriginSpec))
+			Expect(testClient.Get(ctx, client.ObjectKeyFromObject(runtime), runtime)).To(Succeed())
+			containers, _, _ = unstructured.NestedSlice(runtime.Object, "spec", "containers")
+			Expect(containers[0]).To(HaveKeyWithValue("image", "mirror.internal/meta/"+testModelName+":1.0.0"))
+
+			By("restoring the images once no mirror matches")
+			Expect(testClient.Delete(ctx, mirrorSet)).To(Succeed())
+			patch = client.MergeFrom(app.DeepCopy())
+			app.Spec.Mirrors = nil
+			metav1.SetMetaDataAnnotation(&app.ObjectMeta, Annotation_RefreshRequestedAt, "2024-06-01T12:00:00Z")
+			Expect(testClient.Patch(ctx, app, patch)).To(Succeed())
+			_, err = reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(testClient.Get(ctx, client.ObjectKeyFromObject(runtime), runtime)).To(Succeed())
+			containers, _, _ = unstructured.NestedSlice(runtime.Object, "spec", "containers")
+			Expect(containers[0]).To(HaveKeyWithValue("image", "nvcr.io/nim/meta/"+testModelName+":1.0.0"))
+			Expect(runtime.GetAnnotations()).NotTo(HaveKey(Annotation_SourceImages))
+			Expect(testClient.Get(ctx, cmKey, cm)).To(Succeed())
+			Expect(json.Unmarshal([]byte(cm.Data[testModelName]), model)).To(Succeed())
+			Expect(model.Image).To(Equal("nvcr.io/nim/meta/" + testModelName))
+		})
+
+		It("should rewrite the serving runtime images without a content update", func(ctx SpecContext) {
+			runtime := newServingRuntime()
+			runtime.SetNamespace(namespace.Name)
+			containers, _, _ := unstructured.NestedSlice(runtime.Object, "spec", "containers")
+			containers[0].(map[string]any)["image"] = "nvcr.io/nim/meta/" + testModelName + ":1.0.0"
+			Expect(unstructured.SetNestedSlice(runtime.Object, containers, "spec", "containers")).To(Succeed())
+			Expect(testClient.Create(ctx, runtime)).To(Succeed())
+			_, err := reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(testClient.Get(ctx, request.NamespacedName, app)).To(Succeed())
+			lastContentUpdate := app.Status.LastContentUpdateTime
+
+			mirrorSet := &unstructured.Unstructured{}
+			mirrorSet.SetAPIVersion("config.openshift.io/v1")
+			mirrorSet.SetKind("ImageDigestMirrorSet")
+			mirrorSet.SetGenerateName("nim-mirrors-")
+			Expect(unstructured.SetNestedSlice(mirrorSet.Object, []any{
+				map[string]any{"source": "nvcr.io/nim", "mirrors": []any{"registry.internal/nim"}},
+			}, "spec", "imageDigestMirrors")).To(Succeed())
+			Expect(testClient.Create(ctx, mirrorSet)).To(Succeed())
+			DeferCleanup(func(ctx SpecContext) {
+				Expect(client.IgnoreNotFound(testClient.Delete(ctx, mirrorSet))).To(Succeed())
+			})
+			Expect(reconciler.mapImageMirror(mirrorSet)).To(ContainElement(request))
+
+			_, err = reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(testClient.Get(ctx, request.NamespacedName, app)).To(Succeed())
+			Expect(app.Status.LastContentUpdateTime).To(Equal(lastContentUpdate))
+			Expect(testClient.Get(ctx, client.ObjectKeyFromObject(runtime), runtime)).To(Succeed())
+			containers, _, _ = unstructured.NestedSlice(runtime.Object, "spec", "containers")
+			Expect(containers[0]).To(HaveKeyWithValue("image", "registry.internal/nim/meta/"+testModelName+":1.0.0"))
+		})
+
+		It("should create the NIM PVC and report it pending", func(ctx SpecContext) {
+			_, err := reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(testClient.Get(ctx, request.NamespacedName, app)).To(Succeed())
+
+			pvc := &corev1.PersistentVolumeClaim{}
+			pvcKey := client.ObjectKey{Name: Name_NimPvc, Namespace: namespace.Name}
+			Expect(testClient.Get(ctx, pvcKey, pvc)).To(Succeed())
+			Expect(metav1.IsControlledBy(pvc, app)).To(BeTrue())
+			Expect(pvc.Spec.AccessModes).To(ConsistOf(defaultNimPvcAccessMode))
+			Expect(pvc.Spec.Resources.Requests.Storage().Equal(defaultNimPvcSize)).To(BeTrue())
+
+			condition := meta.FindStatusCondition(app.Status.Conditions, Condition_NimPvcBound)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal(Reason_NimPvcPending))
+		})
+
+		// bindAndExpandPvc is used for binding the NIM PVC created with the storage class and requesting its expansion
+		bindAndExpandPvc := func(ctx SpecContext, storageClassName string) *corev1.PersistentVolumeClaim {
+			Expect(testClient.Get(ctx, request.NamespacedName, app)).To(Succeed())
+			patch := client.MergeFrom(app.DeepCopy())
+			app.Spec.Storage.StorageClassName = &storageClassName
+			Expect(testClient.Patch(ctx, app, patch)).To(Succeed())
+			_, err := reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+
+			pvc := &corev1.PersistentVolumeClaim{}
+			pvcKey := client.ObjectKey{Name: Name_NimPvc, Namespace: namespace.Name}
+			Expect(testClient.Get(ctx, pvcKey, pvc)).To(Succeed())
+			Expect(pvc.Spec.StorageClassName).To(HaveValue(Equal(storageClassName)))
+			pvcPatch := client.MergeFrom(pvc.DeepCopy())
+			pvc.Status.Phase = corev1.ClaimBound
+			pvc.Status.Capacity = corev1.ResourceList{corev1.ResourceStorage: defaultNimPvcSize}
+			Expect(testClient.Status().Patch(ctx, pvc, pvcPatch)).To(Succeed())
+
+			Expect(testClient.Get(ctx, request.NamespacedName, app)).To(Succeed())
+			patch = client.MergeFrom(app.DeepCopy())
+			size := resource.MustParse("100Gi")
+			app.Spec.Storage.Size = &size
+			Expect(testClient.Patch(ctx, app, patch)).To(Succeed())
+			_, err = reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(testClient.Get(ctx, pvcKey, pvc)).To(Succeed())
+			Expect(testClient.Get(ctx, request.NamespacedName, app)).To(Succeed())
+			return pvc
+		}
+
+		It("should report the NIM PVC bound and expand it", func(ctx SpecContext) {
+			storageClass := newTestStorageClass(true)
+			Expect(testClient.Create(ctx, storageClass)).To(Succeed())
+			DeferCleanup(cleanup, storageClass)
+
+			pvc := bindAndExpandPvc(ctx, storageClass.GetName())
+			Expect(pvc.Spec.Resources.Requests.Storage().Equal(resource.MustParse("100Gi"))).To(BeTrue())
+			Expect(meta.IsStatusConditionTrue(app.Status.Conditions, Condition_NimPvcBound)).To(BeTrue())
+		})
+
+		It("should report NIM PVC expansions rejected by the cluster", func(ctx SpecContext) {
+			storageClass := newTestStorageClass(false)
+			Expect(testClient.Create(ctx, storageClass)).To(Succeed())
+			DeferCleanup(cleanup, storageClass)
+
+			pvc := bindAndExpandPvc(ctx, storageClass.GetName())
+			Expect(pvc.Spec.Resources.Requests.Storage().Equal(defaultNimPvcSize)).To(BeTrue())
+			condition := meta.FindStatusCondition(app.Status.Conditions, Condition_NimPvcBound)
+			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+			Expect(condition.Message).To(ContainSubstring("expansion rejected"))
+		})
+
+		requestWarmup := func(ctx SpecContext, models ...string) {
+			Expect(testClient.Get(ctx, request.NamespacedName, app)).To(Succeed())
+			patch := client.MergeFrom(app.DeepCopy())
+			app.Spec.Warmup.Models = models
+			Expect(testClient.Patch(ctx, app, patch)).To(Succeed())
+			_, err := reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(testClient.Get(ctx, request.NamespacedName, app)).To(Succeed())
+		}
+
+		It("should warm up the listed models and report their progress", func(ctx SpecContext) {
+			requestWarmup(ctx, testModelName, "missing-model")
+
+			Expect(app.Status.Warmups).To(HaveLen(2))
+			Expect(app.Status.Warmups[0].Model).To(Equal(testModelName))
+			Expect(app.Status.Warmups[0].Version).To(Equal("1.0.0"))
+			Expect(app.Status.Warmups[0].Phase).To(Equal(v1beta1.WarmupPhaseRunning))
+			Expect(app.Status.Warmups[0].JobRef).NotTo(BeNil())
+			Expect(app.Status.Warmups[1].Model).To(Equal("missing-model"))
+			Expect(app.Status.Warmups[1].Phase).To(Equal(v1beta1.WarmupPhaseFailed))
+			Expect(app.Status.Warmups[1].JobRef).To(BeNil())
+
+			job := &batchv1.Job{}
+			jobKey := client.ObjectKey{Name: app.Status.Warmups[0].JobRef.Name, Namespace: namespace.Name}
+			Expect(testClient.Get(ctx, jobKey, job)).To(Succeed())
+			Expect(metav1.IsControlledBy(job, app)).To(BeTrue())
+			Expect(job.Spec.Template.Spec.Containers[0].Image).To(Equal("nvcr.io/nim/meta/" + testModelName + ":1.0.0"))
+			Expect(job.Spec.Template.Spec.Volumes[0].PersistentVolumeClaim.ClaimName).To(Equal(Name_NimPvc))
+
+			By("reporting the completion")
+			patch := client.MergeFrom(job.DeepCopy())
+			now := metav1.Now()
+			job.Status.StartTime = &now
+			job.Status.CompletionTime = &now
+			job.Status.Succeeded = 1
+			job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}
+			Expect(testClient.Status().Patch(ctx, job, patch)).To(Succeed())
+
+			_, err := reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(testClient.Get(ctx, request.NamespacedName, app)).To(Succeed())
+			Expect(app.Status.Warmups[0].Phase).To(Equal(v1beta1.WarmupPhaseSucceeded))
+			Expect(app.Status.Warmups[0].CompletionTime).NotTo(BeNil())
+		})
+
+		It("should re-run the warm-up when the catalog version changes", func(ctx SpecContext) {
+			requestWarmup(ctx, testModelName)
+			previousJobKey := client.ObjectKey{Name: app.Status.Warmups[0].JobRef.Name, Namespace: namespace.Name}
+
+			cm := &corev1.ConfigMap{}
+			cmKey := client.ObjectKey{Name: Name_ContentConfigMap, Namespace: namespace.Name}
+			Expect(testClient.Get(ctx, cmKey, cm)).To(Succeed())
+			model := &ngc.Model{}
+			Expect(json.Unmarshal([]byte(cm.Data[testModelName]), model)).To(Succeed())
+			model.LatestTag = "1.1.0"
+			encoded, err := json.Marshal(model)
+			Expect(err).NotTo(HaveOccurred())
+			patch := client.MergeFrom(cm.DeepCopy())
+			cm.Data[testModelName] = string(encoded)
+			Expect(testClient.Patch(ctx, cm, patch)).To(Succeed())
+
+			_, err = reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(testClient.Get(ctx, request.NamespacedName, app)).To(Succeed())
+			Expect(app.Status.Warmups).To(HaveLen(1))
+			Expect(app.Status.Warmups[0].Version).To(Equal("1.1.0"))
+			Expect(app.Status.Warmups[0].JobRef.Name).NotTo(Equal(previousJobKey.Name))
+
+			job := &batchv1.Job{}
+			Expect(testClient.Get(ctx, client.ObjectKey{Name: app.Status.Warmups[0].JobRef.Name, Namespace: namespace.Name},
+				job)).To(Succeed())
+			Expect(job.Spec.Template.Spec.Containers[0].Image).To(HaveSuffix(":1.1.0"))
+			Expect(k8serrors.IsNotFound(testClient.Get(ctx, previousJobKey, &batchv1.Job{}))).To(BeTrue())
+		})
+
+		It("should skip the warm-up of models with no version", func(ctx SpecContext) {
+			requestWarmup(ctx, testModelName)
+			previousJobKey := client.ObjectKey{Name: app.Status.Warmups[0].JobRef.Name, Namespace: namespace.Name}
+
+			cm := &corev1.ConfigMap{}
+			cmKey := client.ObjectKey{Name: Name_ContentConfigMap, Namespace: namespace.Name}
+			Expect(testClient.Get(ctx, cmKey, cm)).To(Succeed())
+			model := &ngc.Model{}
+			Expect(json.Unmarshal([]byte(cm.Data[testModelName]), model)).To(Succeed())
+			model.Tags, model.LatestTag = nil, ""
+			encoded, err := json.Marshal(model)
+			Expect(err).NotTo(HaveOccurred())
+			patch := client.MergeFrom(cm.DeepCopy())
+			cm.Data[testModelName] = string(encoded)
+			Expect(testClient.Patch(ctx, cm, patch)).To(Succeed())
+
+			_, err = reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(testClient.Get(ctx, request.NamespacedName, app)).To(Succeed())
+			Expect(app.Status.Warmups).To(HaveLen(1))
+			Expect(app.Status.Warmups[0].Phase).To(Equal(v1beta1.WarmupPhaseSkipped))
+			Expect(app.Status.Warmups[0].Version).To(BeEmpty())
+			Expect(app.Status.Warmups[0].JobRef).To(BeNil())
+			Expect(k8serrors.IsNotFound(testClient.Get(ctx, previousJobKey, &batchv1.Job{}))).To(BeTrue())
+		})
+
+		rotateApiKey := func(ctx SpecContext, apiKey string) {
+			secret := &corev1.Secret{}
+			secretKey := client.ObjectKey{Name: "odh-nim-app-api-key", Namespace: namespace.Name}
+			Expect(testClient.Get(ctx, secretKey, secret)).To(Succeed())
+			patch := client.MergeFrom(secret.DeepCopy())
+			secret.Data[Key_ApiKey] = []byte(apiKey)
+			Expect(testClient.Patch(ctx, secret, patch)).To(Succeed())
+
+			Expect(testClient.Get(ctx, request.NamespacedName, app)).To(Succeed())
+			patch = client.MergeFrom(app.DeepCopy())
+			metav1.SetMetaDataAnnotation(&app.ObjectMeta, Annotation_ValidateRequestedAt, apiKey)
+			Expect(testClient.Patch(ctx, app, patch)).To(Succeed())
+
+			_, err := reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(testClient.Get(ctx, request.NamespacedName, app)).To(Succeed())
+		}
+
+		It("should keep the last known good API key when the rotated key is rejected", func(ctx SpecContext) {
+			_, err := reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+
+			rotateApiKey(ctx, "my-invalid-api-key")
+			Expect(app.Status.ApiKeyHash).To(Equal(hashApiKey(testValidApiKey)))
+			condition := meta.FindStatusCondition(app.Status.Conditions, Condition_ApiKeyValidated)
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal(Reason_ApiKeyInvalid))
+			Expect(condition.Message).To(ContainSubstring("keeping the last known good API key"))
+
+			apiKeySecret := &corev1.Secret{}
+			apiKeySecretKey := client.ObjectKey{Name: Name_ApiKeySecret, Namespace: namespace.Name}
+			Expect(testClient.Get(ctx, apiKeySecretKey, apiKeySecret)).To(Succeed())
+			Expect(apiKeySecret.Data).To(HaveKeyWithValue(Key_NgcApiKey, []byte(testValidApiKey)))
+		})
+
+		It("should swap the API key and restart the NIM InferenceServices when accepted", func(ctx SpecContext) {
+			_, err := reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(testClient.Get(ctx, request.NamespacedName, app)).To(Succeed())
+			Expect(app.Status.ApiKeyHash).To(Equal(hashApiKey(testValidApiKey)))
+
+			nimIsvc := newTestInferenceService(namespace.Name, "nim-isvc", Name_ServingRuntime)
+			Expect(testClient.Create(ctx, nimIsvc)).To(Succeed())
+			otherIsvc := newTestInferenceService(namespace.Name, "other-isvc", "other-runtime")
+			Expect(testClient.Create(ctx, otherIsvc)).To(Succeed())
+
+			rotateApiKey(ctx, testRotatedApiKey)
+			Expect(app.Status.ApiKeyHash).To(Equal(hashApiKey(testRotatedApiKey)))
+			Expect(meta.IsStatusConditionTrue(app.Status.Conditions, Condition_ApiKeyValidated)).To(BeTrue())
+
+			apiKeySecret := &corev1.Secret{}
+			apiKeySecretKey := client.ObjectKey{Name: Name_ApiKeySecret, Namespace: namespace.Name}
+			Expect(testClient.Get(ctx, apiKeySecretKey, apiKeySecret)).To(Succeed())
+			Expect(apiKeySecret.Data).To(HaveKeyWithValue(Key_NgcApiKey, []byte(testRotatedApiKey)))
+
+			Expect(testClient.Get(ctx, client.ObjectKeyFromObject(nimIsvc), nimIsvc)).To(Succeed())
+			hash, _, _ := unstructured.NestedString(nimIsvc.Object, "spec", "predictor", "annotations", Annotation_ApiKeyHash)
+			Expect(hash).To(Equal(hashApiKey(testRotatedApiKey)))
+			Expect(testClient.Get(ctx, client.ObjectKeyFromObject(otherIsvc), otherIsvc)).To(Succeed())
+			hash, _, _ = unstructured.NestedString(otherIsvc.Object, "spec", "predictor", "annotations", Annotation_ApiKeyHash)
+			Expect(hash).To(BeEmpty())
+			Expect(drainEvents(recorder)).To(ContainElement(HavePrefix("Normal " + Reason_ApiKeySwapped)))
+		})
+
+		It("should schedule the next validation and content update", func(ctx SpecContext) {
+			result, err := reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(testClient.Get(ctx, request.NamespacedName, app)).To(Succeed())
+			Expect(result.RequeueAfter).To(BeNumerically("~",
+				defaultScheduleInterval+scheduleJitter(app, defaultScheduleInterval), time.Minute))
+			Expect(app.Status.LastValidationTime).NotTo(BeNil())
+			Expect(app.Status.LastContentUpdateTime).NotTo(BeNil())
+		})
+
+		It("should validate and update the content when scheduled", func(ctx SpecContext) {
+			_, err := reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+
+			// the last runs are older than the interval
+			Expect(testClient.Get(ctx, request.NamespacedName, app)).To(Succeed())
+			patch := client.MergeFrom(app.DeepCopy())
+			lastRun := metav1.NewTime(time.Now().Add(-2 * time.Hour).Truncate(time.Second))
+			app.Status.LastValidationTime = &lastRun
+			app.Status.LastContentUpdateTime = &lastRun
+			Expect(testClient.Status().Patch(ctx, app, patch)).To(Succeed())
+
+			patch = client.MergeFrom(app.DeepCopy())
+			app.Spec.Schedule.Interval = &metav1.Duration{Duration: time.Hour}
+			Expect(testClient.Patch(ctx, app, patch)).To(Succeed())
+
+			result, err := reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(BeNumerically("~", time.Hour+scheduleJitter(app, time.Hour), time.Minute))
+
+			Expect(testClient.Get(ctx, request.NamespacedName, app)).To(Succeed())
+			Expect(app.Status.LastValidationTime.After(lastRun.Time)).To(BeTrue())
+			Expect(app.Status.LastContentUpdateTime.After(lastRun.Time)).To(BeTrue())
+		})
+
+		It("should validate and update the content when requested using annotations", func(ctx SpecContext) {
+			_, err := reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(testClient.Get(ctx, request.NamespacedName, app)).To(Succeed())
+			lastValidationTime := app.Status.LastValidationTime
+			lastContentUpdateTime := app.Status.LastContentUpdateTime
+
+			// make sure the new run times are distinguishable, timestamps are in seconds
+			time.Sleep(time.Second)
+
+			patch := client.MergeFrom(app.DeepCopy())
+			metav1.SetMetaDataAnnotation(&app.ObjectMeta, Annotation_ValidateRequestedAt, "2024-06-01T10:00:00Z")
+			metav1.SetMetaDataAnnotation(&app.ObjectMeta, Annotation_RefreshRequestedAt, "2024-06-01T11:00:00Z")
+			Expect(testClient.Patch(ctx, app, patch)).To(Succeed())
+			generation := app.Generation
+
+			_, err = reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(testClient.Get(ctx, request.NamespacedName, app)).To(Succeed())
+			Expect(app.Generation).To(Equal(generation))
+			Expect(app.Status.ObservedValidateRequestedAt).To(Equal("2024-06-01T10:00:00Z"))
+			Expect(app.Status.ObservedRefreshRequestedAt).To(Equal("2024-06-01T11:00:00Z"))
+			Expect(app.Status.LastValidationTime.After(lastValidationTime.Time)).To(BeTrue())
+			Expect(app.Status.LastContentUpdateTime.After(lastContentUpdateTime.Time)).To(BeTrue())
+
+			// observed requests are not repeated
+			lastValidationTime = app.Status.LastValidationTime
+			_, err = reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(testClient.Get(ctx, request.NamespacedName, app)).To(Succeed())
+			Expect(app.Status.LastValidationTime.Equal(lastValidationTime)).To(BeTrue())
+		})
+
+		It("should record the lifecycle transitions only once", func(ctx SpecContext) {
+			_, err := reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(drainEvents(recorder)).To(ContainElements(
+				HavePrefix("Normal "+Reason_ApiKeyValidatedSuccessfully),
+				HavePrefix("Normal "+Reason_NgcSecretsCreated),
+				HavePrefix("Normal "+Reason_ServingTemplateRendered),
+				HavePrefix("Normal "+Reason_ContentUpdatedSuccessfully),
+			))
+
+			// periodic validations and content updates with the same results are not recorded
+			Expect(testClient.Get(ctx, request.NamespacedName, app)).To(Succeed())
+			patch := client.MergeFrom(app.DeepCopy())
+			metav1.SetMetaDataAnnotation(&app.ObjectMeta, Annotation_ValidateRequestedAt, "2024-06-01T10:00:00Z")
+			metav1.SetMetaDataAnnotation(&app.ObjectMeta, Annotation_RefreshRequestedAt, "2024-06-01T11:00:00Z")
+			Expect(testClient.Patch(ctx, app, patch)).To(Succeed())
+
+			_, err = reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(drainEvents(recorder)).To(BeEmpty())
+		})
+
+		It("should remove the finalizer when the OdhNimApp is deleted", func(ctx SpecContext) {
+			_, err := reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(testClient.Delete(ctx, app)).To(Succeed())
+			_, err = reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(testClient.Get(ctx, request.NamespacedName, app)).NotTo(Succeed())
+		})
+	})
+})
+
+// newTestApp is used for creating an OdhNimApp referencing the testing API key secret
+func newTestApp(namespace string) *v1beta1.OdhNimApp {
+	return &v1beta1.OdhNimApp{
+		ObjectMeta: metav1.ObjectMeta{Name: "odh-nim-app", Namespace: namespace},
+		Spec: v1beta1.OdhNimAppSpec{
+			ApiKey: v1beta1.OdhNimAppSpecApiKey{
+				Validate:  true,
+				SecretRef: &corev1.ObjectReference{Name: "odh-nim-app-api-key", Namespace: namespace},
+			},
+			Content: v1beta1.OdhNimAppSpecContent{Update: true},
+		},
+	}
+}
+
+// newTestInferenceService is used for creating a KServe InferenceService served by the named runtime
+func newTestInferenceService(namespace, name, runtimeName string) *unstructured.Unstructured {
+	isvc := &unstructured.Unstructured{Object: map[string]any{
+		"spec": map[string]any{
+			"predictor": map[string]any{
+				"model": map[string]any{
+					"runtime":     runtimeName,
+					"modelFormat": map[string]any{"name": testModelName},
+				},
+			},
+		},
+	}}
+	isvc.SetGroupVersionKind(inferenceServiceListGvk.GroupVersion().WithKind("InferenceService"))
+	isvc.SetName(name)
+	isvc.SetNamespace(namespace)
+	return isvc
+}
+
+// newTestStorageClass is used for creating a StorageClass allowing or denying volume expansion, StorageClasses are
+// cluster scoped, the name is generated
+func newTestStorageClass(allowVolumeExpansion bool) *unstructured.Unstructured {
+	storageClass := &unstructured.Unstructured{Object: map[string]any{
+		"provisioner":          "example.com/nim-test",
+		"allowVolumeExpansion": allowVolumeExpansion,
+	}}
+	storageClass.SetAPIVersion("storage.k8s.io/v1")
+	storageClass.SetKind("StorageClass")
+	storageClass.SetGenerateName("nim-test-")
+	return storageClass
+}
+
+// newTestApiKeySecret is used for creating the testing API key secret
+func newTestApiKeySecret(namespace, apiKey string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "odh-nim-app-api-key",
+			Namespace: namespace,
+			Labels:    map[string]string{Label_NimApp: "true"},
+		},
+		Data: map[string][]byte{Key_ApiKey: []byte(apiKey)},
+	}
+}
+
+var _ = DescribeTable("Content verification",
+	func(models, previousModels int, requested, expected bool) {
+		data := map[string]string{}
+		for i := 0; i < models; i++ {
+			data[fmt.Sprintf("model-%d", i)] = "{}"
+		}
+		Expect(verifyContent(data, previousModels, requested) == nil).To(Equal(expected))
+	},
+	Entry("initial content", 0, 0, false, true),
+	Entry("grown catalog", 12, 10, false, true),
+	Entry("slightly shrunk catalog", 5, 10, false, true),
+	Entry("drastically shrunk catalog", 4, 10, false, false),
+	Entry("empty catalog", 0, 10, false, false),
+	Entry("requested shrunk catalog", 4, 10, true, true),
+	Entry("requested empty catalog", 0, 10, true, true),
+)
@@ -0,0 +1,152 @@
+// Copyright (c) 2024 Red Hat, Inc.
+
+package controllers
+
+// This file hosts the diffing of the content catalog between content updates. A model republished without tag changes
+// is detected by the image digest of its latest tag, or by its updated date for catalogs not reporting digests. The
+// last catalogHistoryLimit diffs are kept in the content history ConfigMap, encoded as a JSON list keyed by
+// Key_ContentHistory, the latest diff first.
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/opendatahub-io/odh-nim-operator/pkg/ngc"
+	"sort"
+)
+
+// catalogHistoryLimit is the number of diffs kept in the content history ConfigMap
+const catalogHistoryLimit = 10
+
+// catalogDiff is used for encapsulating the changes of the content catalog between content updates
+type catalogDiff struct {
+	Time    string             `json:"time"`
+	Added   []string           `json:"added,omitempty"`
+	Removed []string           `json:"removed,omitempty"`
+	Updated []catalogModelDiff `json:"updated,omitempty"`
+}
+
+// catalogModelDiff is used for encapsulating the changes of a model available before and after a content update
+type catalogModelDiff struct {
+	Name           string   `json:"name"`
+	AddedTags      []string `json:"addedTags,omitempty"`
+	RemovedTags    []string `json:"removedTags,omitempty"`
+	PreviousLatest string   `json:"previousLatestTag,omitempty"`
+	Latest         string   `json:"latestTag,omitempty"`
+	PreviousDigest string   `json:"previousDigest,omitempty"`
+	Digest         string   `json:"digest,omitempty"`
+	Republished    bool     `json:"republished,omitempty"`
+}
+
+// diffCatalogs is used for diffing the content ConfigMap data before and after a content update, models failing to
+// decode are diffed by name only. Returns nil if nothing changed.
+func diffCatalogs(previous, current map[string]string) *catalogDiff {
+	diff := &catalogDiff{}
+	for name, encoded := range current {
+		previousEncoded, found := previous[name]
+		if !found {
+			diff.Added = append(diff.Added, name)
+			continue
+		}
+		if previousEncoded == encoded {
+			continue
+		}
+		previousModel, currentModel := ngc.Model{}, ngc.Model{}
+		if json.Unmarshal([]byte(previousEncoded), &previousModel) != nil ||
+			json.Unmarshal([]byte(encoded), &currentModel) != nil {
+			continue
+		}
+		if modelDiff := diffModels(previousModel, currentModel); modelDiff != nil {
+			modelDiff.Name = name
+			diff.Updated = append(diff.Updated, *modelDiff)
+		}
+	}
+	for name := range previous {
+		if _, found := current[name]; !found {
+			diff.Removed = append(diff.Removed, name)
+		}
+	}
+
+	if len(diff.Added) == 0 && len(diff.Removed) == 0 && len(diff.Updated) == 0 {
+		return nil
+	}
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Slice(diff.Updated, func(i, j int) bool { return diff.Updated[i].Name < diff.Updated[j].Name })
+	return diff
+}
+
+// diffModels is used for diffing the tags, the latest tag, and the latest image digest of a model, a model with
+// neither tag changed is reported republished if its digest changed. The updated date is only compared if either
+// model has no digest. Returns nil if nothing changed.
+func diffModels(previous, current ngc.Model) *catalogModelDiff {
+	diff := &catalogModelDiff{
+		AddedTags:   subtractTags(current.Tags, previous.Tags),
+		RemovedTags: subtractTags(previous.Tags, current.Tags),
+	}
+	if previous.LatestTag != current.LatestTag {
+		diff.PreviousLatest = previous.LatestTag
+		diff.Latest = current.LatestTag
+	}
+	digested := previous.Digest != "" && current.Digest != ""
+	if digested && previous.Digest != current.Digest {
+		diff.PreviousDigest = previous.Digest
+		diff.Digest = current.Digest
+	}
+
+	if len(diff.AddedTags) == 0 && len(diff.RemovedTags) == 0 && diff.Latest == "" && diff.PreviousLatest == "" {
+		if (digested && diff.Digest == "") || (!digested && previous.UpdatedDate == current.UpdatedDate) {
+			return nil
+		}
+		diff.Republished = true
+	}
+	return diff
+}
+
+// subtractTags is used for listing the tags found in tags but not in other
+func subtractTags(tags, other []string) []string {
+	otherSet := make(map[string]bool, len(other))
+	for _, tag := range other {
+		otherSet[tag] = true
+	}
+	var subtracted []string
+	for _, tag := range tags {
+		if !otherSet[tag] {
+			subtracted = append(subtracted, tag)
+		}
+	}
+	return subtracted
+}
+
+// updatedModels is used for listing the names of the models updated by the diff
+func (d *catalogDiff) updatedModels() []string {
+	names := make([]string, 0, len(d.Updated))
+	for _, modelDiff := range d.Updated {
+		names = append(names, modelDiff.Name)
+	}
+	return names
+}
+
+// summary is used for summarizing the diff in a single line, for the OdhNimApp status and events
+func (d *catalogDiff) summary() string {
+	return fmt.Sprintf("%d models added, %d removed, %d updated", len(d.Added), len(d.Removed), len(d.Updated))
+}
+
+// appendHistory is used for prepending the diff to the encoded content history, keeping the last catalogHistoryLimit
+// diffs, a history failing to decode is replaced
+func appendHistory(encoded string, diff *catalogDiff) (string, error) {
+	var history []catalogDiff
+	if encoded != "" && json.Unmarshal([]byte(encoded), &history) != nil {
+		history = nil
+	}
+
+	history = append([]catalogDiff{*diff}, history...)
+	if len(history) > catalogHistoryLimit {
+		history = history[:catalogHistoryLimit]
+	}
+
+	updated, err := json.Marshal(history)
+	if err != nil {
+		return "", err
+	}
+	return string(updated), nil
+}
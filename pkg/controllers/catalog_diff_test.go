@@ -0,0 +1,92 @@
+// Copyright (c) 2024 Red Hat, Inc.
+
+package controllers
+
+import (
+	"encoding/json"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/opendatahub-io/odh-nim-operator/pkg/ngc"
+	"strconv"
+)
+
+var _ = Describe("Catalog diff", func() {
+	encode := func(model ngc.Model) string {
+		encoded, err := json.Marshal(model)
+		Expect(err).NotTo(HaveOccurred())
+		return string(encoded)
+	}
+
+	previousModel := ngc.Model{
+		Name:        testModelName,
+		Tags:        []string{"1.0.0", "latest"},
+		LatestTag:   "1.0.0",
+		UpdatedDate: "2024-06-01T00:00:00.000Z",
+		Digest:      "sha256:1111",
+	}
+
+	DescribeTable("diffing models",
+		func(mutate func(*ngc.Model), expected *catalogModelDiff) {
+			model := previousModel
+			model.Tags = append([]string(nil), previousModel.Tags...)
+			mutate(&model)
+			Expect(diffModels(previousModel, model)).To(Equal(expected))
+		},
+		Entry("unchanged", func(*ngc.Model) {}, nil),
+		Entry("new latest tag", func(model *ngc.Model) {
+			model.Tags = append(model.Tags, "1.1.0")
+			model.LatestTag = "1.1.0"
+			model.UpdatedDate = "2024-07-01T00:00:00.000Z"
+		}, &catalogModelDiff{AddedTags: []string{"1.1.0"}, PreviousLatest: "1.0.0", Latest: "1.1.0"}),
+		Entry("removed tag", func(model *ngc.Model) {
+			model.Tags = []string{"1.0.0"}
+		}, &catalogModelDiff{RemovedTags: []string{"latest"}}),
+		Entry("republished", func(model *ngc.Model) {
+			model.Digest = "sha256:2222"
+		}, &catalogModelDiff{PreviousDigest: "sha256:1111", Digest: "sha256:2222", Republished: true}),
+		Entry("updated with the same digest", func(model *ngc.Model) {
+			model.UpdatedDate = "2024-07-01T00:00:00.000Z"
+		}, nil),
+		Entry("republished without a digest", func(model *ngc.Model) {
+			model.Digest = ""
+			model.UpdatedDate = "2024-07-01T00:00:00.000Z"
+		}, &catalogModelDiff{Republished: true}),
+	)
+
+	It("should diff added, removed, and updated models", func() {
+		updatedModel := previousModel
+		updatedModel.Digest = "sha256:2222"
+		previous := map[string]string{
+			testModelName: encode(previousModel),
+			"removed-b":   "{}",
+			"removed-a":   "{}",
+		}
+		current := map[string]string{
+			testModelName: encode(updatedModel),
+			"added":       "{}",
+		}
+
+		diff := diffCatalogs(previous, current)
+		Expect(diff).NotTo(BeNil())
+		Expect(diff.Added).To(Equal([]string{"added"}))
+		Expect(diff.Removed).To(Equal([]string{"removed-a", "removed-b"}))
+		Expect(diff.updatedModels()).To(Equal([]string{testModelName}))
+		Expect(diff.summary()).To(Equal("1 models added, 2 removed, 1 updated"))
+
+		Expect(diffCatalogs(current, current)).To(BeNil())
+	})
+
+	It("should keep the last diffs in the history", func() {
+		var history string
+		for i := 0; i < catalogHistoryLimit+2; i++ {
+			var err error
+			history, err = appendHistory(history, &catalogDiff{Time: strconv.Itoa(i), Added: []string{"model"}})
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		var decoded []catalogDiff
+		Expect(json.Unmarshal([]byte(history), &decoded)).To(Succeed())
+		Expect(decoded).To(HaveLen(catalogHistoryLimit))
+		Expect(decoded[0].Time).To(Equal(strconv.Itoa(catalogHistoryLimit + 1)))
+	})
+})
@@ -2,21 +2,110 @@
 
 package controllers
 
-import ctrl "sigs.k8s.io/controller-runtime"
+import (
+	"context"
+	"github.com/opendatahub-io/odh-nim-operator/api/v1beta1"
+	"github.com/opendatahub-io/odh-nim-operator/pkg/ngc"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
 
 // +kubebuilder:rbac:groups="",resources=configmaps;secrets,verbs=get;list;watch;create;patch;delete
-// +kubebuilder:rbac:groups="",resources=events,verbs=create
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=persistentvolumeclaims,verbs=get;list;watch;create;patch;delete
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;delete
+// +kubebuilder:rbac:groups=config.openshift.io,resources=imagedigestmirrorsets;imagetagmirrorsets,verbs=get;list;watch
+// +kubebuilder:rbac:groups=config.openshift.io,resources=proxies,verbs=get;list;watch
 // +kubebuilder:rbac:groups=coordination.k8s.io,resources=leases,verbs=get;create;update
+// +kubebuilder:rbac:groups=monitoring.coreos.com,resources=prometheusrules,verbs=get;list;watch;create;patch;delete
 // +kubebuilder:rbac:groups=nim.opendatahub.io,resources=odhnimapps,verbs=get;list;watch;create;patch;delete
+// +kubebuilder:rbac:groups=nim.opendatahub.io,resources=odhnimapps/status,verbs=get;patch
+// +kubebuilder:rbac:groups=nim.opendatahub.io,resources=odhnimapps/finalizers,verbs=update
+// +kubebuilder:rbac:groups=operator.openshift.io,resources=imagecontentsourcepolicies,verbs=get;list;watch
+// +kubebuilder:rbac:groups=serving.kserve.io,resources=inferenceservices,verbs=get;list;patch
+// +kubebuilder:rbac:groups=serving.kserve.io,resources=servingruntimes,verbs=get;list;patch
+// +kubebuilder:rbac:groups=template.openshift.io,resources=templates,verbs=get;list;watch;create;patch;delete
 
 const (
 	Finalizer_NimAppCleanup = "nim.opendatahub.io/cleanup_finalizer"
 	Label_NimApp            = "nim.opendatahub.io/nim-app"
+	Label_OdhDashboard      = "opendatahub.io/dashboard"
+	Label_PropagatedFrom    = "nim.opendatahub.io/propagated-from"
+	Label_Warmup            = "nim.opendatahub.io/warmup"
+
+	Annotation_ContentSchemaVersion = "nim.opendatahub.io/content-schema-version"
+	Annotation_OperatorVersion      = "nim.opendatahub.io/operator-version"
+	Annotation_ValidateRequestedAt  = "nim.opendatahub.io/validate-requested-at"
+	Annotation_RefreshRequestedAt   = "nim.opendatahub.io/refresh-requested-at"
+	Annotation_ApiKeyHash           = "nim.opendatahub.io/api-key-hash"
+	Annotation_WarmupModel          = "nim.opendatahub.io/warmup-model"
+	Annotation_WarmupVersion        = "nim.opendatahub.io/warmup-version"
+	Annotation_SourceImages         = "nim.opendatahub.io/source-images"
+
+	Condition_ApiKeyValidated   = "ApiKeyValidated"
+	Condition_ContentUpdated    = "ContentUpdated"
+	Condition_SecretsPropagated = "SecretsPropagated"
+	Condition_NimPvcBound       = "NimPvcBound"
+
+	Reason_ApiKeyValidatedSuccessfully   = "ApiKeyValidatedSuccessfully"
+	Reason_ApiKeyValidationFailed        = "ApiKeyValidationFailed"
+	Reason_ApiKeyInvalid                 = "ApiKeyInvalid"
+	Reason_ApiKeyValidationSkipped       = "ApiKeyValidationSkipped"
+	Reason_NgcUnreachable                = "NgcUnreachable"
+	Reason_NgcRateLimited                = "NgcRateLimited"
+	Reason_ApiKeySecretUnlabeled         = "ApiKeySecretUnlabeled"
+	Reason_ContentUpdatedSuccessfully    = "ContentUpdatedSuccessfully"
+	Reason_ContentUpdateFailed           = "ContentUpdateFailed"
+	Reason_ContentDegraded               = "ContentDegraded"
+	Reason_SecretsPropagatedSuccessfully = "SecretsPropagatedSuccessfully"
+	Reason_SecretsPropagationFailed      = "SecretsPropagationFailed"
+	Reason_NimPvcBound                   = "NimPvcBound"
+	Reason_NimPvcPending                 = "NimPvcPending"
+	Reason_NimPvcLost                    = "NimPvcLost"
+	Reason_ContentChanged                = "ContentChanged"
+	Reason_ServingTemplateRendered       = "ServingTemplateRendered"
+	Reason_ServingTemplateReverted       = "ServingTemplateReverted"
+	Reason_NgcSecretsCreated             = "NgcSecretsCreated"
+	Reason_ApiKeySwapped                 = "ApiKeySwapped"
+	Reason_WarmupStarted                 = "WarmupStarted"
+	Reason_NimAppCreated                 = "NimAppCreated"
+	Reason_ValidationRequested           = "ValidationRequested"
+	Reason_ApiKeySecretDeleted           = "ApiKeySecretDeleted"
+	Reason_GeneratedResourcesDeleted     = "GeneratedResourcesDeleted"
+
+	Key_ApiKey         = "api_key"
+	Key_NgcApiKey      = "NGC_API_KEY"
+	Key_ContentHistory = "history"
+	Key_CaBundle       = "ca-bundle.crt"
+
+	Name_NimApp           = "odh-nim-app"
+	Name_ContentConfigMap = "odh-nim-app-content"
+	Name_ContentHistory   = "odh-nim-app-content-history"
+	Name_ServingTemplate  = "nvidia-nim-serving-template"
+	Name_ServingRuntime   = "nvidia-nim-runtime"
+	Name_ApiKeySecret     = "nvidia-nim-secrets"
+	Name_PullSecret       = "ngc-secret"
+	Name_NimPvc           = "nim-pvc"
+	Name_PrometheusRule   = "odh-nim-alerts"
+	Name_ClusterProxy     = "cluster"
 )
 
 // ControllerOptions is encapsulating the global options for use with all controllers
 type ControllerOptions struct {
-	Manager ctrl.Manager
+	Manager                 ctrl.Manager
+	NgcClient               *ngc.Client
+	EnablePrometheusRules   bool
+	PrometheusRuleNamespace string
+	OfflineCatalog          string
+	SkipApiKeyValidation    bool
+	TrustedCaBundle         string
+	PropagationSelector     string
 }
 
 // controllerSetups is used for registering controllers for loading
@@ -31,3 +120,80 @@ func SetupControllers(opts ControllerOptions) error {
 	}
 	return nil
 }
+
+// hasNimAppLabel is used for checking if an object is labeled with nim.opendatahub.io/nim-app set to true
+func hasNimAppLabel(obj client.Object) bool {
+	value, found := obj.GetLabels()[Label_NimApp]
+	return found && value == "true"
+}
+
+// isRequestPending is used for checking if a request annotation, i.e. nim.opendatahub.io/validate-requested-at, was
+// set to a value not yet observed by the controller, values are opaque, we recommend using the request timestamp
+func isRequestPending(app *v1beta1.OdhNimApp, annotation, observed string) bool {
+	requested := app.Annotations[annotation]
+	return requested != "" && requested != observed
+}
+
+// setCondition is used for setting a condition in the OdhNimApp status, observing the OdhNimApp generation
+func setCondition(app *v1beta1.OdhNimApp, condition metav1.Condition) {
+	condition.ObservedGeneration = app.Generation
+	meta.SetStatusCondition(&app.Status.Conditions, condition)
+}
+
+// isConditionCurrent is used for checking if the OdhNimApp status already holds the condition for the OdhNimApp
+// generation, so patching the status can be skipped
+func isConditionCurrent(app *v1beta1.OdhNimApp, condition metav1.Condition) bool {
+	current := meta.FindStatusCondition(app.Status.Conditions, condition.Type)
+	return current != nil && current.Status == condition.Status && current.Reason == condition.Reason &&
+		current.Message == condition.Message && current.ObservedGeneration == app.Generation
+}
+
+// recordConditionEvent is used for recording an event for a condition about to be set in the OdhNimApp status, only
+// transitions are recorded so periodic reconciliations don't spam the event stream, repeated transitions are further
+// aggregated by the recorder. Conditions other than True are recorded as warnings.
+func recordConditionEvent(recorder record.EventRecorder, app *v1beta1.OdhNimApp, condition metav1.Condition) {
+	current := meta.FindStatusCondition(app.Status.Conditions, condition.Type)
+	if current != nil && current.Status == condition.Status && current.Reason == condition.Reason &&
+		current.Message == condition.Message {
+		return
+	}
+	eventType := corev1.EventTypeNormal
+	if condition.Status != metav1.ConditionTrue {
+		eventType = corev1.EventTypeWarning
+	}
+	recorder.Event(app, eventType, condition.Reason, condition.Message)
+}
+
+// patchStatus is used for patching the OdhNimApp status with the modifications made by mutate, observing the OdhNimApp
+// generation
+func patchStatus(ctx context.Context, c client.Client, app *v1beta1.OdhNimApp, mutate func()) error {
+	patch := client.MergeFrom(app.DeepCopy())
+	mutate()
+	app.Status.ObservedGeneration = app.Generation
+	return c.Status().Patch(ctx, app, patch)
+}
+
+// patchCondition is used for setting a condition in the OdhNimApp status and patching it
+func patchCondition(ctx context.Context, c client.Client, app *v1beta1.OdhNimApp, condition metav1.Condition) error {
+	return patchStatus(ctx, c, app, func() { setCondition(app, condition) })
+}
+
+// deleteControlled is used for deleting objects controlled by the OdhNimApp, objects not found or not controlled by
+// the OdhNimApp are ignored
+func deleteControlled(ctx context.Context, c client.Client, app *v1beta1.OdhNimApp, objs ...client.Object) error {
+	for _, obj := range objs {
+		if err := c.Get(ctx, client.ObjectKeyFromObject(obj), obj); err != nil {
+			if k8serrors.IsNotFound(err) {
+				continue
+			}
+			return err
+		}
+		if !metav1.IsControlledBy(obj, app) {
+			continue
+		}
+		if err := c.Delete(ctx, obj); err != nil && !k8serrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,266 @@
+// Copyright (c) 2024 Red Hat, Inc.
+
+package controllers
+
+// This file hosts the rewriting of image references for disconnected clusters mirroring the NGC registry. Mirrors are
+// read from the OdhNimApp spec and from the cluster ImageDigestMirrorSet, ImageTagMirrorSet and
+// ImageContentSourcePolicy objects. The cluster only redirects digest pulls to the mirrors of the digest mirror
+// objects, NIM images are pulled by tag, so image references are rewritten in the content ConfigMap and in the NIM
+// ServingRuntimes, the ServingRuntimes record their original images so they are restored once the mirrors are removed.
+// The ServingRuntimes are rewritten on every reconciliation, and the mirror objects are watched when installed.
+// The mirror objects and the ServingRuntimes are handled as unstructured objects, so we don't need the OpenShift config
+// and operator APIs or the KServe API as dependencies.
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/opendatahub-io/odh-nim-operator/api/v1beta1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+	"sort"
+	"strings"
+)
+
+const mirrorOriginSpec = "OdhNimApp"
+
+// mirrorSources are the cluster objects holding registry mirrors, and the spec path of their source/mirrors lists
+var mirrorSources = []struct {
+	listGvk schema.GroupVersionKind
+	path    []string
+}{
+	{
+		listGvk: schema.GroupVersionKind{Group: "config.openshift.io", Version: "v1", Kind: "ImageDigestMirrorSetList"},
+		path:    []string{"spec", "imageDigestMirrors"},
+	},
+	{
+		listGvk: schema.GroupVersionKind{Group: "config.openshift.io", Version: "v1", Kind: "ImageTagMirrorSetList"},
+		path:    []string{"spec", "imageTagMirrors"},
+	},
+	{
+		listGvk: schema.GroupVersionKind{
+			Group:   "operator.openshift.io",
+			Version: "v1alpha1",
+			Kind:    "ImageContentSourcePolicyList",
+		},
+		path: []string{"spec", "repositoryDigestMirrors"},
+	},
+}
+
+var servingRuntimeListGvk = schema.GroupVersionKind{
+	Group:   "serving.kserve.io",
+	Version: "v1alpha1",
+	Kind:    "ServingRuntimeList",
+}
+
+// imageMirror is used for encapsulating a registry mirror, images under source are pulled from mirror, the origin is
+// the object the mirror was read from
+type imageMirror struct {
+	source string
+	mirror string
+	origin string
+}
+
+// getImageMirrors is used for listing the registry mirrors, the OdhNimApp spec mirrors first, then the cluster ones,
+// only the first mirror of every cluster source is used. Clusters without the mirror APIs are ignored.
+func getImageMirrors(ctx context.Context, c client.Client, app *v1beta1.OdhNimApp) ([]imageMirror, error) {
+	logger := log.FromContext(ctx)
+
+	var mirrors []imageMirror
+	for source, mirror := range app.Spec.Mirrors {
+		mirrors = append(mirrors, imageMirror{source: source, mirror: mirror, origin: mirrorOriginSpec})
+	}
+	sort.Slice(mirrors, func(i, j int) bool { return mirrors[i].source < mirrors[j].source })
+
+	for _, mirrorSource := range mirrorSources {
+		list := &unstructured.UnstructuredList{}
+		list.SetGroupVersionKind(mirrorSource.listGvk)
+		if err := c.List(ctx, list); err != nil {
+			if meta.IsNoMatchError(err) {
+				logger.V(1).Info("mirror API is not installed", "kind", mirrorSource.listGvk.Kind)
+				continue
+			}
+			return nil, err
+		}
+
+		kind := strings.TrimSuffix(mirrorSource.listGvk.Kind, "List")
+		for _, item := range list.Items {
+			entries, _, _ := unstructured.NestedSlice(item.Object, mirrorSource.path...)
+			for _, entry := range entries {
+				fields, ok := entry.(map[string]any)
+				if !ok {
+					continue
+				}
+				source, _, _ := unstructured.NestedString(fields, "source")
+				targets, _, _ := unstructured.NestedStringSlice(fields, "mirrors")
+				if source == "" || len(targets) == 0 {
+					continue
+				}
+				mirrors = append(mirrors, imageMirror{
+					source: source,
+					mirror: targets[0],
+					origin: fmt.Sprintf("%s/%s", kind, item.GetName()),
+				})
+			}
+		}
+	}
+	return mirrors, nil
+}
+
+// resolveImage is used for rewriting an image reference to its mirror, the mirror with the longest matching source is
+// used, the first one listed for equally long sources. Returns the image unchanged and nil if no mirror matches.
+func resolveImage(image string, mirrors []imageMirror) (string, *imageMirror) {
+	var resolved *imageMirror
+	for i := range mirrors {
+		source := mirrors[i].source
+		if !strings.HasPrefix(image, source) {
+			continue
+		}
+		if rest := image[len(source):]; rest != "" && !strings.ContainsAny(rest[:1], "/:@") {
+			// a partial repository name match, i.e. nvcr.io/nim/meta matching nvcr.io/nim/meta-llama
+			continue
+		}
+		if resolved == nil || len(source) > len(resolved.source) {
+			resolved = &mirrors[i]
+		}
+	}
+
+	if resolved == nil {
+		return image, nil
+	}
+	return resolved.mirror + image[len(resolved.source):], resolved
+}
+
+// reportImageMirrors is used for reporting the mirrors catalog images resolved to in the OdhNimApp status, with the
+// number of images resolved to each, sorted by source
+func reportImageMirrors(resolved map[imageMirror]int32) []v1beta1.OdhNimAppStatusImageMirror {
+	var report []v1beta1.OdhNimAppStatusImageMirror
+	for mirror, images := range resolved {
+		report = append(report, v1beta1.OdhNimAppStatusImageMirror{
+			Source: mirror.source,
+			Mirror: mirror.mirror,
+			Origin: mirror.origin,
+			Images: images,
+		})
+	}
+	sort.Slice(report, func(i, j int) bool { return report[i].Source < report[j].Source })
+	return report
+}
+
+// watchImageMirrors is used for watching the cluster mirror objects with the controller builder, mapping their events
+// with mapFunc, clusters without the mirror APIs are not watched
+func watchImageMirrors(mgr ctrl.Manager, bldr *builder.Builder, mapFunc handler.MapFunc) (*builder.Builder, error) {
+	for _, mirrorSource := range mirrorSources {
+		gvk := mirrorSource.listGvk
+		gvk.Kind = strings.TrimSuffix(gvk.Kind, "List")
+		if _, err := mgr.GetRESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version); err != nil {
+			if meta.IsNoMatchError(err) {
+				continue
+			}
+			return nil, err
+		}
+		obj := &unstructured.Unstructured{}
+		obj.SetGroupVersionKind(gvk)
+		bldr = bldr.Watches(&source.Kind{Type: obj}, handler.EnqueueRequestsFromMapFunc(mapFunc))
+	}
+	return bldr, nil
+}
+
+// rewriteServingRuntimes is used for rewriting the container images of the NIM ServingRuntimes deployed in a namespace
+// to their mirrors, clusters without KServe are ignored. The original images are recorded by container name in the
+// source-images annotation, images are resolved from their original, and restored once no mirror matches.
+func rewriteServingRuntimes(ctx context.Context, c client.Client, namespace string, mirrors []imageMirror) error {
+	logger := log.FromContext(ctx)
+
+	runtimes := &unstructured.UnstructuredList{}
+	runtimes.SetGroupVersionKind(servingRuntimeListGvk)
+	if err := c.List(ctx, runtimes, client.InNamespace(namespace)); err != nil {
+		if meta.IsNoMatchError(err) {
+			logger.V(1).Info("KServe is not installed, no ServingRuntimes to rewrite")
+			return nil
+		}
+		return err
+	}
+
+	for i := range runtimes.Items {
+		runtime := &runtimes.Items[i]
+		if runtime.GetName() != Name_ServingRuntime {
+			continue
+		}
+		sourceImages := map[string]string{}
+		if encoded, found := runtime.GetAnnotations()[Annotation_SourceImages]; found {
+			if err := json.Unmarshal([]byte(encoded), &sourceImages); err != nil {
+				logger.Info("ignoring malformed source images annotation", "name", runtime.GetName(),
+					"namespace", namespace)
+				sourceImages = map[string]string{}
+			}
+		}
+
+		patch := client.MergeFrom(runtime.DeepCopy())
+		containers, _, _ := unstructured.NestedSlice(runtime.Object, "spec", "containers")
+		modified := false
+		for _, container := range containers {
+			fields, ok := container.(map[string]any)
+			if !ok {
+				continue
+			}
+			name, _, _ := unstructured.NestedString(fields, "name")
+			image, _, _ := unstructured.NestedString(fields, "image")
+			source, recorded := sourceImages[name]
+			if !recorded {
+				source = image
+			}
+
+			resolved, mirror := resolveImage(source, mirrors)
+			switch {
+			case mirror != nil:
+				sourceImages[name] = source
+				if resolved != image {
+					logger.Info("rewrote ServingRuntime image", "name", runtime.GetName(), "namespace", namespace,
+						"image", resolved, "origin", mirror.origin)
+				}
+			case recorded:
+				delete(sourceImages, name)
+				logger.Info("restored ServingRuntime image", "name", runtime.GetName(), "namespace", namespace,
+					"image", source)
+			}
+			if resolved != image {
+				fields["image"] = resolved
+				modified = true
+			}
+		}
+
+		annotations := runtime.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		encoded, _ := json.Marshal(sourceImages)
+		if _, found := annotations[Annotation_SourceImages]; found && len(sourceImages) == 0 {
+			delete(annotations, Annotation_SourceImages)
+			modified = true
+		} else if len(sourceImages) > 0 && annotations[Annotation_SourceImages] != string(encoded) {
+			annotations[Annotation_SourceImages] = string(encoded)
+			modified = true
+		}
+		runtime.SetAnnotations(annotations)
+
+		if !modified {
+			continue
+		}
+
+		if err := unstructured.SetNestedSlice(runtime.Object, containers, "spec", "containers"); err != nil {
+			return err
+		}
+		if err := c.Patch(ctx, runtime, patch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+// Copyright (c) 2024 Red Hat, Inc.
+
+package controllers
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = DescribeTable("Image mirror resolution",
+	func(image, expectedImage, expectedOrigin string) {
+		mirrors := []imageMirror{
+			{source: "nvcr.io/nim/meta", mirror: "mirror.internal/meta", origin: mirrorOriginSpec},
+			{source: "nvcr.io/nim", mirror: "registry.internal/nim", origin: "ImageDigestMirrorSet/nim"},
+			{source: "nvcr.io/nim", mirror: "other.internal/nim", origin: "ImageContentSourcePolicy/nim"},
+		}
+
+		resolved, mirror := resolveImage(image, mirrors)
+		Expect(resolved).To(Equal(expectedImage))
+		if expectedOrigin == "" {
+			Expect(mirror).To(BeNil())
+		} else {
+			Expect(mirror.origin).To(Equal(expectedOrigin))
+		}
+	},
+	Entry("longest source wins", "nvcr.io/nim/meta/llama3-8b-instruct", "mirror.internal/meta/llama3-8b-instruct",
+		mirrorOriginSpec),
+	Entry("first listed source wins", "nvcr.io/nim/mistralai/mistral-7b-instruct",
+		"registry.internal/nim/mistralai/mistral-7b-instruct", "ImageDigestMirrorSet/nim"),
+	Entry("tags are kept", "nvcr.io/nim/nvidia-embed:1.0.0", "registry.internal/nim/nvidia-embed:1.0.0",
+		"ImageDigestMirrorSet/nim"),
+	Entry("digests are kept", "nvcr.io/nim/meta@sha256:abc", "mirror.internal/meta@sha256:abc", mirrorOriginSpec),
+	Entry("partial repository names don't match", "nvcr.io/nim/meta-llama/model",
+		"registry.internal/nim/meta-llama/model", "ImageDigestMirrorSet/nim"),
+	Entry("other registries are not mirrored", "quay.io/nim/model", "quay.io/nim/model", ""),
+)
@@ -0,0 +1,61 @@
+// Copyright (c) 2024 Red Hat, Inc.
+
+package controllers
+
+// This file hosts the handling of the NIM KServe InferenceServices deployed by users from the serving Template. The
+// InferenceServices are handled as unstructured objects, so we don't need the KServe API as a dependency.
+
+import (
+	"context"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+var inferenceServiceListGvk = schema.GroupVersionKind{
+	Group:   "serving.kserve.io",
+	Version: "v1beta1",
+	Kind:    "InferenceServiceList",
+}
+
+// restartInferenceServices is used for restarting the NIM InferenceServices in a namespace after an API key swap, the
+// predictor pods are annotated with the hash of the new API key, rolling out a new revision. InferenceServices not
+// using the NIM ServingRuntime or already annotated with the hash are ignored, clusters without KServe are ignored.
+func restartInferenceServices(ctx context.Context, c client.Client, namespace, apiKeyHash string) error {
+	logger := log.FromContext(ctx)
+
+	isvcs := &unstructured.UnstructuredList{}
+	isvcs.SetGroupVersionKind(inferenceServiceListGvk)
+	if err := c.List(ctx, isvcs, client.InNamespace(namespace)); err != nil {
+		if meta.IsNoMatchError(err) {
+			logger.V(1).Info("KServe is not installed, no InferenceServices to restart")
+			return nil
+		}
+		return err
+	}
+
+	for i := range isvcs.Items {
+		isvc := &isvcs.Items[i]
+		runtimeName, _, _ := unstructured.NestedString(isvc.Object, "spec", "predictor", "model", "runtime")
+		if runtimeName != Name_ServingRuntime {
+			continue
+		}
+		hash, _, _ := unstructured.NestedString(isvc.Object, "spec", "predictor", "annotations", Annotation_ApiKeyHash)
+		if hash == apiKeyHash {
+			continue
+		}
+
+		patch := client.MergeFrom(isvc.DeepCopy())
+		if err := unstructured.SetNestedField(
+			isvc.Object, apiKeyHash, "spec", "predictor", "annotations", Annotation_ApiKeyHash); err != nil {
+			return err
+		}
+		if err := c.Patch(ctx, isvc, patch); err != nil {
+			return err
+		}
+		logger.Info("restarted InferenceService", "name", isvc.GetName(), "namespace", isvc.GetNamespace())
+	}
+	return nil
+}
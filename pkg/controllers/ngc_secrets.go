@@ -0,0 +1,62 @@
+// Copyright (c) 2024 Red Hat, Inc.
+
+package controllers
+
+// This file hosts the rendering of the Secrets derived from the NGC API key. The pull Secret is used by the NIM
+// ServingRuntime for pulling images from the NGC registry, and the API key Secret is used by the NIM containers for
+// authenticating with NGC (check serving_template.go).
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/opendatahub-io/odh-nim-operator/pkg/ngc"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ngcRegistryUsername is the fixed username NGC expects when authenticating with an API key
+const ngcRegistryUsername = "$oauthtoken"
+
+// dockerConfigJson is used for encoding the .dockerconfigjson key of the pull Secret
+type dockerConfigJson struct {
+	Auths map[string]dockerConfigAuth `json:"auths"`
+}
+
+// dockerConfigAuth is used for encoding the credentials of a registry in the pull Secret
+type dockerConfigAuth struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Auth     string `json:"auth"`
+}
+
+// renderPullSecret is used for rendering the desired state of the NGC registry pull Secret into the secret object
+func renderPullSecret(secret *corev1.Secret, apiKey string) error {
+	config, err := json.Marshal(dockerConfigJson{Auths: map[string]dockerConfigAuth{
+		ngc.DefaultRegistry: {
+			Username: ngcRegistryUsername,
+			Password: apiKey,
+			Auth:     base64.StdEncoding.EncodeToString([]byte(ngcRegistryUsername + ":" + apiKey)),
+		},
+	}})
+	if err != nil {
+		return err
+	}
+
+	secret.Type = corev1.SecretTypeDockerConfigJson
+	secret.Data = map[string][]byte{corev1.DockerConfigJsonKey: config}
+	return nil
+}
+
+// renderApiKeySecret is used for rendering the desired state of the NGC API key Secret into the secret object
+func renderApiKeySecret(secret *corev1.Secret, apiKey string) {
+	secret.Type = corev1.SecretTypeOpaque
+	secret.Data = map[string][]byte{Key_NgcApiKey: []byte(apiKey)}
+}
+
+// hashApiKey is used for hashing the API key, the hash is recorded in the OdhNimApp status and used for restarting the
+// NIM InferenceServices, so the key itself is never exposed
+func hashApiKey(apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(sum[:])
+}
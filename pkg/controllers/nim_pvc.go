@@ -0,0 +1,53 @@
+// Copyright (c) 2024 Red Hat, Inc.
+
+package controllers
+
+// This file hosts the rendering of the NIM PVC. The PVC holds the model cache shared by the NIM ServingRuntime pods
+// (check serving_template.go). PVCs are mostly immutable, the storage class and access mode are only set when the PVC
+// is created, later modifications of the size can only expand it.
+
+import (
+	"github.com/opendatahub-io/odh-nim-operator/api/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// defaultNimPvcSize and defaultNimPvcAccessMode are used when the OdhNimApp storage doesn't set them, the NIM PVC is
+// mounted by every NIM ServingRuntime pod of the namespace, a ReadWriteOnce one is only shared by pods on the same node
+var (
+	defaultNimPvcSize       = resource.MustParse("50Gi")
+	defaultNimPvcAccessMode = corev1.ReadWriteMany
+)
+
+// renderNimPvc is used for rendering the desired state of the NIM PVC into the pvc object, returns true if the
+// requested storage is smaller than the PVC storage, these are ignored as PVCs can't shrink. Expansions are delayed
+// until the PVC is bound.
+func renderNimPvc(pvc *corev1.PersistentVolumeClaim, storage v1beta1.OdhNimAppSpecStorage) bool {
+	size := defaultNimPvcSize
+	if storage.Size != nil && !storage.Size.IsZero() {
+		size = *storage.Size
+	}
+
+	if pvc.CreationTimestamp.IsZero() {
+		accessMode := defaultNimPvcAccessMode
+		if storage.AccessMode != "" {
+			accessMode = storage.AccessMode
+		}
+		pvc.Spec.AccessModes = []corev1.PersistentVolumeAccessMode{accessMode}
+		pvc.Spec.StorageClassName = storage.StorageClassName
+	}
+
+	current, found := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+	if found && size.Cmp(current) < 0 {
+		return true
+	}
+	if !pvc.CreationTimestamp.IsZero() && pvc.Status.Phase != corev1.ClaimBound {
+		// only bound PVCs can be expanded, the expansion is applied once bound
+		return false
+	}
+	if pvc.Spec.Resources.Requests == nil {
+		pvc.Spec.Resources.Requests = corev1.ResourceList{}
+	}
+	pvc.Spec.Resources.Requests[corev1.ResourceStorage] = size
+	return false
+}
@@ -0,0 +1,107 @@
+// Copyright (c) 2024 Red Hat, Inc.
+
+package controllers
+
+// This file hosts the rendering of the PrometheusRule alerting on the NIM integration health, built on the metrics in
+// the metrics package. The PrometheusRule is handled as an unstructured object, so we don't need the Prometheus
+// Operator API as a dependency.
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var prometheusRuleGvk = schema.GroupVersionKind{
+	Group:   "monitoring.coreos.com",
+	Version: "v1",
+	Kind:    "PrometheusRule",
+}
+
+// alertingRule is used for encapsulating a rule of the PrometheusRule
+type alertingRule struct {
+	alert       string
+	expr        string
+	duration    string
+	severity    string
+	summary     string
+	description string
+}
+
+// alertingRules are the rules of the odh-nim group, per OdhNimApp alerts carry the OdhNimApp app_namespace and
+// app_name labels
+var alertingRules = []alertingRule{
+	{
+		alert:    "OdhNimApiKeyInvalid",
+		expr:     "odh_nim_api_key_valid == 0",
+		duration: "15m",
+		severity: "warning",
+		summary:  "NGC API key rejected",
+		description: "The NGC API key of OdhNimApp {{ $labels.app_namespace }}/{{ $labels.app_name }} was rejected " +
+			"by its last validation.",
+	},
+	{
+		alert:    "OdhNimCatalogStale",
+		expr:     "time() - odh_nim_content_next_update_timestamp_seconds > 3600",
+		duration: "15m",
+		severity: "warning",
+		summary:  "NIM catalog not updated as scheduled",
+		description: "The NIM catalog of OdhNimApp {{ $labels.app_namespace }}/{{ $labels.app_name }} is overdue " +
+			"for an update by more than an hour.",
+	},
+	{
+		alert:       "OdhNimNgcRequestsFailing",
+		expr:        `sum by (code) (increase(odh_nim_ngc_requests_total{code=~"5..|429"}[30m])) >= 3`,
+		duration:    "15m",
+		severity:    "warning",
+		summary:     "NGC requests failing",
+		description: "NGC responded with status {{ $labels.code }} to {{ $value }} requests in the last 30 minutes.",
+	},
+	{
+		alert: "OdhNimNgcUnreachable",
+		expr: `increase(odh_nim_api_key_validations_total{result="NgcUnreachable"}[1h]) >= 3 or ` +
+			`increase(odh_nim_catalog_refresh_duration_seconds_count{result="failure"}[1h]) >= 3`,
+		duration: "15m",
+		severity: "warning",
+		summary:  "NGC operations failing repeatedly",
+		description: "API key validations or catalog refreshes of OdhNimApp {{ $labels.app_namespace }}/" +
+			"{{ $labels.app_name }} failed repeatedly in the last hour.",
+	},
+	{
+		alert:    "OdhNimAppFinalizerStuck",
+		expr:     "time() - odh_nim_app_deletion_timestamp_seconds > 600",
+		duration: "5m",
+		severity: "warning",
+		summary:  "OdhNimApp deletion stuck",
+		description: "OdhNimApp {{ $labels.app_namespace }}/{{ $labels.app_name }} was requested for deletion " +
+			"more than 10 minutes ago, check its finalizers.",
+	},
+}
+
+// renderPrometheusRule is used for rendering the NIM alerting rules into the PrometheusRule spec, any other spec field
+// is dropped
+func renderPrometheusRule(rule *unstructured.Unstructured) {
+	rules := make([]interface{}, 0, len(alertingRules))
+	for _, alertingRule := range alertingRules {
+		rules = append(rules, map[string]interface{}{
+			"alert": alertingRule.alert,
+			"expr":  alertingRule.expr,
+			"for":   alertingRule.duration,
+			"labels": map[string]interface{}{
+				"severity": alertingRule.severity,
+			},
+			"annotations": map[string]interface{}{
+				"summary":     alertingRule.summary,
+				"description": alertingRule.description,
+			},
+		})
+	}
+
+	rule.Object["spec"] = map[string]interface{}{
+		"groups": []interface{}{
+			map[string]interface{}{
+				"name":  "odh-nim",
+				"rules": rules,
+			},
+		},
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright (c) 2024 Red Hat, Inc.
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// PrometheusRuleController is used for creating the PrometheusRule alerting on the NIM integration health, manual
+// modifications of the PrometheusRule are reverted. The PrometheusRule is not owned by any object, it's kept when the
+// operator is uninstalled, and deleted when the operator starts with the rules disabled.
+type PrometheusRuleController struct {
+	client.Client
+	Key types.NamespacedName
+}
+
+// SetupWithManager is used for setting up the controller with a manager (check the init function)
+// Note the channel source, it triggers the creation of the PrometheusRule when the controller starts
+func (r *PrometheusRuleController) SetupWithManager(mgr ctrl.Manager) error {
+	rule := &unstructured.Unstructured{}
+	rule.SetGroupVersionKind(prometheusRuleGvk)
+	rule.SetName(r.Key.Name)
+	rule.SetNamespace(r.Key.Namespace)
+
+	start := make(chan event.GenericEvent, 1)
+	start <- event.GenericEvent{Object: rule}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("odh-nim-prometheus-rule-controller").
+		For(rule, builder.WithPredicates(predicate.NewPredicateFuncs(func(obj client.Object) bool {
+			return client.ObjectKeyFromObject(obj) == r.Key
+		}))).
+		Watches(&source.Channel{Source: start}, &handler.EnqueueRequestForObject{}).
+		Complete(r)
+}
+
+// rbac markers are in controllers.go
+
+func (r *PrometheusRuleController) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx).WithName("prometheus-rule-controller")
+	ctx = log.IntoContext(ctx, logger)
+	// all funcs we invoke in this context should use 'logger := log.FromContext(ctx)' to get the correct logger
+	logger.V(1).Info(fmt.Sprintf("got request for PrometheusRule %s", req.NamespacedName))
+
+	rule := &unstructured.Unstructured{}
+	rule.SetGroupVersionKind(prometheusRuleGvk)
+	rule.SetName(r.Key.Name)
+	rule.SetNamespace(r.Key.Namespace)
+
+	result, err := controllerutil.CreateOrPatch(ctx, r.Client, rule, func() error {
+		renderPrometheusRule(rule)
+		return nil
+	})
+	if err != nil {
+		logger.Error(err, "failed reconciling PrometheusRule")
+		return ctrl.Result{}, err
+	}
+	if result != controllerutil.OperationResultNone {
+		logger.Info(fmt.Sprintf("%s PrometheusRule", result), "name", rule.GetName())
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// deletePrometheusRules is used for deleting PrometheusRules left by previous runs of the operator, nothing is done if
+// the Prometheus Operator is not installed
+func deletePrometheusRules(ctx context.Context, c client.Client, keys ...types.NamespacedName) error {
+	logger := log.FromContext(ctx).WithName("prometheus-rule-controller")
+	for _, key := range keys {
+		rule := &unstructured.Unstructured{}
+		rule.SetGroupVersionKind(prometheusRuleGvk)
+		rule.SetName(key.Name)
+		rule.SetNamespace(key.Namespace)
+		if err := c.Delete(ctx, rule); err != nil {
+			if k8serrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+				continue
+			}
+			logger.Error(err, "failed deleting PrometheusRule", "namespace", key.Namespace)
+			return err
+		}
+		logger.Info("deleted PrometheusRule", "name", key.Name, "namespace", key.Namespace)
+	}
+	return nil
+}
+
+// init is used for registering the odh-nim-prometheus-rule controller for loading if enabled, the PrometheusRule is
+// deleted otherwise
+func init() {
+	controllerSetups = append(controllerSetups, func(opts ControllerOptions) error {
+		key := types.NamespacedName{Name: Name_PrometheusRule, Namespace: opts.PrometheusRuleNamespace}
+		if !opts.EnablePrometheusRules {
+			if opts.PrometheusRuleNamespace == "" {
+				return nil
+			}
+			// failing to delete the stale PrometheusRule is not fatal, it's retried on the next start
+			return opts.Manager.Add(manager.RunnableFunc(func(ctx context.Context) error {
+				_ = deletePrometheusRules(ctx, opts.Manager.GetClient(), key)
+				return nil
+			}))
+		}
+		if opts.PrometheusRuleNamespace == "" {
+			return fmt.Errorf("the PrometheusRule namespace is required for enabling the PrometheusRule")
+		}
+		return (&PrometheusRuleController{opts.Manager.GetClient(), key}).SetupWithManager(opts.Manager)
+	})
+}
@@ -0,0 +1,103 @@
+// Copyright (c) 2024 Red Hat, Inc.
+
+package controllers
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("PrometheusRuleController", func() {
+	var reconciler *PrometheusRuleController
+	var request ctrl.Request
+
+	BeforeEach(func(ctx SpecContext) {
+		namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{GenerateName: "prometheus-rule-controller-"}}
+		Expect(testClient.Create(ctx, namespace)).To(Succeed())
+
+		key := types.NamespacedName{Name: Name_PrometheusRule, Namespace: namespace.Name}
+		reconciler = &PrometheusRuleController{testClient, key}
+		request = ctrl.Request{NamespacedName: key}
+	})
+
+	getRule := func(ctx SpecContext) *unstructured.Unstructured {
+		rule := &unstructured.Unstructured{}
+		rule.SetGroupVersionKind(prometheusRuleGvk)
+		Expect(testClient.Get(ctx, request.NamespacedName, rule)).To(Succeed())
+		return rule
+	}
+
+	getAlerts := func(rule *unstructured.Unstructured) []string {
+		groups, _, err := unstructured.NestedSlice(rule.Object, "spec", "groups")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(groups).To(HaveLen(1))
+		rules, _, err := unstructured.NestedSlice(groups[0].(map[string]interface{}), "rules")
+		Expect(err).NotTo(HaveOccurred())
+
+		var alerts []string
+		for _, rule := range rules {
+			alerts = append(alerts, rule.(map[string]interface{})["alert"].(string))
+		}
+		return alerts
+	}
+
+	It("should create the PrometheusRule", func(ctx SpecContext) {
+		_, err := reconciler.Reconcile(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(getAlerts(getRule(ctx))).To(ConsistOf(
+			"OdhNimApiKeyInvalid",
+			"OdhNimCatalogStale",
+			"OdhNimNgcRequestsFailing",
+			"OdhNimNgcUnreachable",
+			"OdhNimAppFinalizerStuck",
+		))
+	})
+
+	It("should describe the alerts with the OdhNimApp series labels", func() {
+		for _, alertingRule := range alertingRules {
+			// the namespace label is the operator namespace set by Prometheus on scrape
+			Expect(alertingRule.description).NotTo(ContainSubstring("$labels.namespace"))
+			Expect(alertingRule.description).NotTo(ContainSubstring("$labels.name "))
+		}
+	})
+
+	It("should revert manual modifications of the PrometheusRule", func(ctx SpecContext) {
+		_, err := reconciler.Reconcile(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+
+		rule := getRule(ctx)
+		patch := client.MergeFrom(rule.DeepCopy())
+		Expect(unstructured.SetNestedSlice(rule.Object, []interface{}{
+			map[string]interface{}{"name": "odh-nim", "rules": []interface{}{}},
+		}, "spec", "groups")).To(Succeed())
+		Expect(testClient.Patch(ctx, rule, patch)).To(Succeed())
+		Expect(getAlerts(getRule(ctx))).To(BeEmpty())
+
+		_, err = reconciler.Reconcile(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(getAlerts(getRule(ctx))).To(HaveLen(len(alertingRules)))
+	})
+
+	It("should delete the PrometheusRule when the rules are disabled", func(ctx SpecContext) {
+		_, err := reconciler.Reconcile(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+		getRule(ctx)
+
+		Expect(deletePrometheusRules(ctx, testClient, request.NamespacedName)).To(Succeed())
+		rule := &unstructured.Unstructured{}
+		rule.SetGroupVersionKind(prometheusRuleGvk)
+		Expect(k8serrors.IsNotFound(testClient.Get(ctx, request.NamespacedName, rule))).To(BeTrue())
+
+		By("ignoring missing PrometheusRules")
+		Expect(deletePrometheusRules(ctx, testClient, request.NamespacedName)).To(Succeed())
+	})
+})
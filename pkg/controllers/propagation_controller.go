@@ -0,0 +1,479 @@
+// Copyright (c) 2024 Red Hat, Inc.
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"github.com/opendatahub-io/odh-nim-operator/api/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+	"sort"
+	"strings"
+)
+
+// propagatedSecretNames are the names of the Secrets generated for the OdhNimApp and copied to the model serving
+// namespaces, the copies keep the same names, so the NIM ServingRuntime can reference them in any namespace
+var propagatedSecretNames = []string{Name_PullSecret, Name_ApiKeySecret}
+
+// propagatedNames are the names of the propagated objects, the Secrets and the NIM PVC mounted by the NIM
+// ServingRuntime, the NIM PVC is not copied, an empty one is created per namespace
+var propagatedNames = append([]string{Name_NimPvc}, propagatedSecretNames...)
+
+// DefaultPropagationSelector selects the data science projects created by the ODH Dashboard, where NIM models are
+// deployed
+const DefaultPropagationSelector = Label_OdhDashboard + "=true"
+
+// PropagationController is used for copying the Secrets generated for the OdhNimApp to the namespaces selected by
+// OdhNimApp.Spec.Propagation, set by the operator, or by the operator DefaultSelector otherwise. Owner references can't
+// cross namespaces, the copies are labeled with nim.opendatahub.io/propagated-from set to the OdhNimApp namespace and
+// garbage collected by this controller, before the OdhNimApp finalizer is removed. Namespaces holding their own
+// OdhNimApp are never propagated to. The NIM PVC mounted by the NIM ServingRuntime is propagated the same way, an empty
+// one is created per namespace with the OdhNimApp storage, NIM PVCs provided by the namespaces are kept.
+type PropagationController struct {
+	client.Client
+	Scheme          *runtime.Scheme
+	Recorder        record.EventRecorder
+	DefaultSelector *metav1.LabelSelector
+}
+
+// SetupWithManager is used for setting up the controller with a manager (check the init function)
+// Note the mapped watches, Namespace, Secret and PVC events are mapped to the OdhNimApp propagating to them, and
+// OdhNimApp events to all the OdhNimApps propagating secrets, as namespaces holding an OdhNimApp are not propagated to
+func (r *PropagationController) SetupWithManager(mgr ctrl.Manager) error {
+	bldr, err := watchImageMirrors(mgr, ctrl.NewControllerManagedBy(mgr).
+		Named("odh-nim-propagation-controller").
+		For(&v1beta1.OdhNimApp{}).
+		Watches(&source.Kind{Type: &corev1.Namespace{}}, handler.EnqueueRequestsFromMapFunc(r.mapNamespace)).
+		Watches(&source.Kind{Type: &corev1.Secret{}}, handler.EnqueueRequestsFromMapFunc(r.mapPropagated)).
+		Watches(&source.Kind{Type: &corev1.PersistentVolumeClaim{}},
+			handler.EnqueueRequestsFromMapFunc(r.mapPropagated)).
+		Watches(&source.Kind{Type: &v1beta1.OdhNimApp{}}, handler.EnqueueRequestsFromMapFunc(r.mapNamespace)),
+		r.mapNamespace)
+	if err != nil {
+		return err
+	}
+	return bldr.Complete(r)
+}
+
+// rbac markers are in controllers.go
+
+func (r *PropagationController) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx).WithName("propagation-controller")
+	ctx = log.IntoContext(ctx, logger)
+	// all funcs we invoke in this context should use 'logger := log.FromContext(ctx)' to get the correct logger
+	logger.V(1).Info(fmt.Sprintf("got request for OdhNimApp %s", req.NamespacedName))
+
+	app := &v1beta1.OdhNimApp{}
+	if err := r.Get(ctx, req.NamespacedName, app); err != nil {
+		if !k8serrors.IsNotFound(err) {
+			logger.Error(err, "failed fetching OdhNimApp")
+			return ctrl.Result{}, err
+		}
+		app = nil
+	}
+
+	// the OdhNimApp was deleted, garbage collect all the copies
+	if app == nil || !app.DeletionTimestamp.IsZero() {
+		if err := deletePropagated(ctx, r.Client, req.Namespace, nil); err != nil {
+			logger.Error(err, "failed deleting propagated copies")
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, nil
+	}
+
+	targets, err := r.getTargetNamespaces(ctx, app)
+	if err != nil {
+		logger.Error(err, "failed listing target namespaces")
+		return ctrl.Result{}, err
+	}
+
+	keep := map[types.NamespacedName]bool{}
+	var conflicts []string
+	for _, name := range propagatedSecretNames {
+		secret := &corev1.Secret{}
+		if err = r.Get(ctx, client.ObjectKey{Name: name, Namespace: app.Namespace}, secret); err != nil {
+			if !k8serrors.IsNotFound(err) {
+				logger.Error(err, "failed fetching source secret", "name", name)
+				return ctrl.Result{}, err
+			}
+			// not generated yet or torn down, the copies are garbage collected
+			continue
+		}
+		if !metav1.IsControlledBy(secret, app) {
+			continue
+		}
+
+		for _, namespace := range targets {
+			propagated, err := r.propagateSecret(ctx, secret, namespace)
+			if err != nil {
+				logger.Error(err, "failed propagating secret", "name", name, "namespace", namespace)
+				return ctrl.Result{}, err
+			}
+			if !propagated {
+				conflicts = append(conflicts, fmt.Sprintf("%s/%s", namespace, name))
+				continue
+			}
+			keep[types.NamespacedName{Name: name, Namespace: namespace}] = true
+		}
+	}
+
+	// the NIM ServingRuntimes deployed in the target namespaces mount the NIM PVC
+	for _, namespace := range targets {
+		if err = r.propagateNimPvc(ctx, app, namespace); err != nil {
+			logger.Error(err, "failed propagating NIM PVC", "namespace", namespace)
+			return ctrl.Result{}, err
+		}
+		keep[types.NamespacedName{Name: Name_NimPvc, Namespace: namespace}] = true
+	}
+
+	if err = deletePropagated(ctx, r.Client, app.Namespace, keep); err != nil {
+		logger.Error(err, "failed deleting propagated copies")
+		return ctrl.Result{}, err
+	}
+
+	// the NIM ServingRuntimes deployed in the target namespaces pull from the registry mirrors as well
+	if len(targets) > 0 {
+		mirrors, err := getImageMirrors(ctx, r.Client, app)
+		if err != nil {
+			logger.Error(err, "failed listing image mirrors")
+			return ctrl.Result{}, err
+		}
+		for _, namespace := range targets {
+			if err = rewriteServingRuntimes(ctx, r.Client, namespace, mirrors); err != nil {
+				logger.Error(err, "failed rewriting serving runtime images", "namespace", namespace)
+				return ctrl.Result{}, err
+			}
+		}
+	}
+
+	if err = r.reportPropagation(ctx, app, targets, conflicts); err != nil {
+		logger.Error(err, "failed patching propagation status")
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// getTargetNamespaces is used for listing the names of the namespaces selected by the OdhNimApp propagation selector,
+// and the namespaces listed, missing namespaces are ignored. The default selector is used if the OdhNimApp sets
+// neither. An empty selector selects nothing, so a selector never selects every namespace. Namespaces holding an
+// OdhNimApp, the OdhNimApp namespace included, are excluded.
+func (r *PropagationController) getTargetNamespaces(ctx context.Context, app *v1beta1.OdhNimApp) ([]string, error) {
+	propagation := r.getPropagation(app)
+	if !isPropagating(propagation) {
+		return nil, nil
+	}
+
+	var namespaces []corev1.Namespace
+	if selector := propagation.NamespaceSelector; selector != nil &&
+		(len(selector.MatchLabels) > 0 || len(selector.MatchExpressions) > 0) {
+		labelSelector, err := metav1.LabelSelectorAsSelector(selector)
+		if err != nil {
+			return nil, err
+		}
+		selected := &corev1.NamespaceList{}
+		if err = r.List(ctx, selected, client.MatchingLabelsSelector{Selector: labelSelector}); err != nil {
+			return nil, err
+		}
+		namespaces = selected.Items
+	}
+	for _, name := range propagation.Namespaces {
+		namespace := corev1.Namespace{}
+		if err := r.Get(ctx, client.ObjectKey{Name: name}, &namespace); err != nil {
+			if k8serrors.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+		namespaces = append(namespaces, namespace)
+	}
+
+	apps := &v1beta1.OdhNimAppList{}
+	if err := r.List(ctx, apps); err != nil {
+		return nil, err
+	}
+	excluded := map[string]bool{app.Namespace: true}
+	for _, other := range apps.Items {
+		excluded[other.Namespace] = true
+	}
+
+	var targets []string
+	for _, namespace := range namespaces {
+		if excluded[namespace.Name] || !namespace.DeletionTimestamp.IsZero() {
+			continue
+		}
+		excluded[namespace.Name] = true
+		targets = append(targets, namespace.Name)
+	}
+	sort.Strings(targets)
+	return targets, nil
+}
+
+// propagateSecret is used for creating or patching the copy of a source Secret in the target namespace, returns false
+// if a Secret not owned by this controller, i.e. not propagated from the source namespace or controlled by an
+// OdhNimApp, already exists, or if the target namespace holds an OdhNimApp, these are never modified. The NIM
+// InferenceServices in the target namespace are restarted when the API key is swapped.
+func (r *PropagationController) propagateSecret(
+	ctx context.Context, source *corev1.Secret, namespace string) (bool, error) {
+	logger := log.FromContext(ctx)
+
+	apps := &v1beta1.OdhNimAppList{}
+	if err := r.List(ctx, apps, client.InNamespace(namespace)); err != nil {
+		return false, err
+	}
+	if len(apps.Items) > 0 {
+		logger.Info("skipping namespace with an OdhNimApp", "name", source.Name, "namespace", namespace)
+		return false, nil
+	}
+
+	propagated := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: source.Name, Namespace: namespace}}
+	if err := r.Get(ctx, client.ObjectKeyFromObject(propagated), propagated); err == nil {
+		if !isPropagatedFrom(propagated, source.Namespace) {
+			logger.Info("skipping unmanaged secret", "name", source.Name, "namespace", namespace)
+			return false, nil
+		}
+	} else if !k8serrors.IsNotFound(err) {
+		return false, err
+	}
+
+	result, err := controllerutil.CreateOrPatch(ctx, r.Client, propagated, func() error {
+		if propagated.Labels == nil {
+			propagated.Labels = map[string]string{}
+		}
+		propagated.Labels[Label_PropagatedFrom] = source.Namespace
+		propagated.Type = source.Type
+		propagated.Data = source.Data
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	if result != controllerutil.OperationResultNone {
+		logger.Info(fmt.Sprintf("%s propagated secret", result), "name", source.Name, "namespace", namespace)
+	}
+
+	// the API key was swapped, restart the NIM InferenceServices using it
+	if result == controllerutil.OperationResultUpdated && source.Name == Name_ApiKeySecret {
+		apiKeyHash := hashApiKey(string(source.Data[Key_NgcApiKey]))
+		if err = restartInferenceServices(ctx, r.Client, namespace, apiKeyHash); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// propagateNimPvc is used for creating or expanding the NIM PVC in the target namespace with the OdhNimApp storage,
+// NIM PVCs not propagated from the OdhNimApp namespace are provided by the namespace and never modified. Expansions
+// rejected by the cluster are not retried.
+func (r *PropagationController) propagateNimPvc(ctx context.Context, app *v1beta1.OdhNimApp, namespace string) error {
+	logger := log.FromContext(ctx)
+
+	pvc := &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: Name_NimPvc, Namespace: namespace}}
+	if err := r.Get(ctx, client.ObjectKeyFromObject(pvc), pvc); err == nil {
+		if !isPropagatedFrom(pvc, app.Namespace) {
+			logger.V(1).Info("keeping the NIM PVC provided by the namespace", "namespace", namespace)
+			return nil
+		}
+	} else if !k8serrors.IsNotFound(err) {
+		return err
+	}
+
+	result, err := controllerutil.CreateOrPatch(ctx, r.Client, pvc, func() error {
+		if pvc.Labels == nil {
+			pvc.Labels = map[string]string{}
+		}
+		pvc.Labels[Label_PropagatedFrom] = app.Namespace
+		renderNimPvc(pvc, app.Spec.Storage)
+		return nil
+	})
+	if err != nil {
+		if pvc.CreationTimestamp.IsZero() || (!k8serrors.IsForbidden(err) && !k8serrors.IsInvalid(err)) {
+			return err
+		}
+		logger.Info("propagated NIM PVC expansion rejected", "namespace", namespace, "reason", err.Error())
+		return nil
+	}
+	if result != controllerutil.OperationResultNone {
+		logger.Info(fmt.Sprintf("%s propagated NIM PVC", result), "name", pvc.Name, "namespace", namespace)
+	}
+	return nil
+}
+
+// deletePropagated is used for deleting the Secrets and NIM PVCs propagated from the source namespace, except for the
+// ones in keep, a nil keep deletes all of them. Copies claimed by an OdhNimApp are never deleted. The AppController
+// also uses it before removing the OdhNimApp finalizer.
+func deletePropagated(
+	ctx context.Context, c client.Client, sourceNamespace string, keep map[types.NamespacedName]bool) error {
+	logger := log.FromContext(ctx)
+
+	selector := client.MatchingLabels{Label_PropagatedFrom: sourceNamespace}
+	secrets := &corev1.SecretList{}
+	if err := c.List(ctx, secrets, selector); err != nil {
+		return err
+	}
+	pvcs := &corev1.PersistentVolumeClaimList{}
+	if err := c.List(ctx, pvcs, selector); err != nil {
+		return err
+	}
+	var propagated []client.Object
+	for i := range secrets.Items {
+		propagated = append(propagated, &secrets.Items[i])
+	}
+	for i := range pvcs.Items {
+		propagated = append(propagated, &pvcs.Items[i])
+	}
+
+	for _, obj := range propagated {
+		if keep[client.ObjectKeyFromObject(obj)] || !isPropagatedFrom(obj, sourceNamespace) {
+			continue
+		}
+		if err := c.Delete(ctx, obj); err != nil && !k8serrors.IsNotFound(err) {
+			return err
+		}
+		logger.Info("deleted propagated object", "name", obj.GetName(), "namespace", obj.GetNamespace())
+	}
+	return nil
+}
+
+// reportPropagation is used for reporting the propagation result in the OdhNimApp status, the status is only patched
+// when the condition changes, the condition is removed when propagation is disabled
+func (r *PropagationController) reportPropagation(
+	ctx context.Context, app *v1beta1.OdhNimApp, targets, conflicts []string) error {
+	if !isPropagating(r.getPropagation(app)) {
+		if meta.FindStatusCondition(app.Status.Conditions, Condition_SecretsPropagated) == nil {
+			return nil
+		}
+		return patchStatus(ctx, r.Client, app, func() {
+			meta.RemoveStatusCondition(&app.Status.Conditions, Condition_SecretsPropagated)
+		})
+	}
+
+	condition := metav1.Condition{
+		Type:    Condition_SecretsPropagated,
+		Status:  metav1.ConditionTrue,
+		Reason:  Reason_SecretsPropagatedSuccessfully,
+		Message: fmt.Sprintf("secrets propagated to %d namespaces", len(targets)),
+	}
+	if len(conflicts) > 0 {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = Reason_SecretsPropagationFailed
+		condition.Message = fmt.Sprintf("unmanaged secrets already exist: %s", strings.Join(conflicts, ", "))
+	}
+
+	if isConditionCurrent(app, condition) {
+		return nil
+	}
+	recordConditionEvent(r.Recorder, app, condition)
+	return patchCondition(ctx, r.Client, app, condition)
+}
+
+// mapNamespace is used for mapping Namespace, OdhNimApp, and cluster mirror object events to requests for all the
+// OdhNimApps propagating secrets, the selectors are evaluated by the reconciler, so namespaces leaving the selector are
+// also handled
+func (r *PropagationController) mapNamespace(_ client.Object) []reconcile.Request {
+	apps := &v1beta1.OdhNimAppList{}
+	if err := r.List(context.Background(), apps); err != nil {
+		log.Log.WithName("propagation-controller").Error(err, "failed listing OdhNimApps")
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, app := range apps.Items {
+		if isPropagating(r.getPropagation(&app)) {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&app)})
+		}
+	}
+	return requests
+}
+
+// mapPropagated is used for mapping Secret and PVC events to requests for the OdhNimApp owning the source object,
+// events for the copies are mapped to the OdhNimApp in the namespace they were propagated from, so modifications are
+// reverted
+func (r *PropagationController) mapPropagated(obj client.Object) []reconcile.Request {
+	if !isPropagatedName(obj.GetName()) {
+		return nil
+	}
+
+	if sourceNamespace, found := obj.GetLabels()[Label_PropagatedFrom]; found {
+		apps := &v1beta1.OdhNimAppList{}
+		if err := r.List(context.Background(), apps, client.InNamespace(sourceNamespace)); err != nil {
+			log.Log.WithName("propagation-controller").Error(err, "failed listing OdhNimApps")
+			return nil
+		}
+		if len(apps.Items) == 0 {
+			// the OdhNimApp is gone, the request will garbage collect the copies
+			key := types.NamespacedName{Name: Name_NimApp, Namespace: sourceNamespace}
+			return []reconcile.Request{{NamespacedName: key}}
+		}
+		return []reconcile.Request{{NamespacedName: client.ObjectKeyFromObject(&apps.Items[0])}}
+	}
+
+	if owner := metav1.GetControllerOf(obj); owner != nil && owner.Kind == "OdhNimApp" {
+		key := types.NamespacedName{Name: owner.Name, Namespace: obj.GetNamespace()}
+		return []reconcile.Request{{NamespacedName: key}}
+	}
+	return nil
+}
+
+// getPropagation is used for getting the propagation of the OdhNimApp, the default selector is used if the OdhNimApp
+// neither selects nor lists namespaces
+func (r *PropagationController) getPropagation(app *v1beta1.OdhNimApp) v1beta1.OdhNimAppSpecPropagation {
+	if isPropagating(app.Spec.Propagation) {
+		return app.Spec.Propagation
+	}
+	return v1beta1.OdhNimAppSpecPropagation{NamespaceSelector: r.DefaultSelector}
+}
+
+// isPropagating is used for checking if a propagation selects or lists namespaces
+func isPropagating(propagation v1beta1.OdhNimAppSpecPropagation) bool {
+	return propagation.NamespaceSelector != nil || len(propagation.Namespaces) > 0
+}
+
+// isPropagatedFrom is used for checking if a Secret or PVC is a copy propagated from the source namespace, copies
+// claimed by an OdhNimApp are controlled by it
+func isPropagatedFrom(obj client.Object, sourceNamespace string) bool {
+	return obj.GetLabels()[Label_PropagatedFrom] == sourceNamespace && metav1.GetControllerOf(obj) == nil
+}
+
+// isPropagatedName is used for checking if a Secret or PVC name is one of the propagated names
+func isPropagatedName(name string) bool {
+	for _, propagatedName := range propagatedNames {
+		if name == propagatedName {
+			return true
+		}
+	}
+	return false
+}
+
+// init is used for registering the propagation controller for loading
+func init() {
+	controllerSetups = append(controllerSetups, func(opts ControllerOptions) error {
+		var defaultSelector *metav1.LabelSelector
+		if opts.PropagationSelector != "" {
+			selector, err := metav1.ParseToLabelSelector(opts.PropagationSelector)
+			if err != nil {
+				return fmt.Errorf("the propagation namespace selector %s is invalid: %w", opts.PropagationSelector, err)
+			}
+			defaultSelector = selector
+		}
+		return (&PropagationController{
+			opts.Manager.GetClient(),
+			opts.Manager.GetScheme(),
+			opts.Manager.GetEventRecorderFor("odh-nim-propagation-controller"),
+			defaultSelector,
+		}).SetupWithManager(opts.Manager)
+	})
+}
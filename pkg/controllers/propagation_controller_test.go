@@ -0,0 +1,367 @@
+// Copyright (c) 2024 Red Hat, Inc.
+
+package controllers
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/opendatahub-io/odh-nim-operator/api/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("PropagationController", func() {
+	var reconciler *PropagationController
+	var namespace, target, other *corev1.Namespace
+	var app *v1beta1.OdhNimApp
+	var request ctrl.Request
+
+	// the test label selecting the target namespace is unique per spec, namespaces can't be deleted in envtest
+	const testPropagationLabel = "nim.opendatahub.io/test-propagation"
+
+	BeforeEach(func(ctx SpecContext) {
+		reconciler = &PropagationController{testClient, testClient.Scheme(), record.NewFakeRecorder(100), nil}
+
+		namespace = &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{GenerateName: "propagation-controller-"}}
+		Expect(testClient.Create(ctx, namespace)).To(Succeed())
+		Expect(testClient.Create(ctx, newTestApiKeySecret(namespace.Name, testValidApiKey))).To(Succeed())
+
+		target = &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "propagation-target-",
+			Labels:       map[string]string{testPropagationLabel: namespace.Name},
+		}}
+		Expect(testClient.Create(ctx, target)).To(Succeed())
+		other = &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{GenerateName: "propagation-other-"}}
+		Expect(testClient.Create(ctx, other)).To(Succeed())
+
+		// generate the source secrets with the app controller
+		app = newTestApp(namespace.Name)
+		app.Spec.Propagation.NamespaceSelector = &metav1.LabelSelector{
+			MatchLabels: map[string]string{testPropagationLabel: namespace.Name},
+		}
+		Expect(testClient.Create(ctx, app)).To(Succeed())
+		request = ctrl.Request{NamespacedName: client.ObjectKeyFromObject(app)}
+		appReconciler := &AppController{testClient, testClient.Scheme(), testNgcClient, record.NewFakeRecorder(100), "", false}
+		_, err := appReconciler.Reconcile(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	getPropagated := func(ctx SpecContext, name, namespace string) (*corev1.Secret, error) {
+		secret := &corev1.Secret{}
+		return secret, testClient.Get(ctx, client.ObjectKey{Name: name, Namespace: namespace}, secret)
+	}
+
+	// the PVC protection finalizer is never removed in envtest, deleted PVCs are kept as terminating
+	expectNimPvcDeleted := func(ctx SpecContext, namespace string) {
+		pvc := &corev1.PersistentVolumeClaim{}
+		err := testClient.Get(ctx, client.ObjectKey{Name: Name_NimPvc, Namespace: namespace}, pvc)
+		if err == nil {
+			Expect(pvc.DeletionTimestamp).NotTo(BeNil())
+		} else {
+			Expect(k8serrors.IsNotFound(err)).To(BeTrue())
+		}
+	}
+
+	It("should propagate the secrets to the selected namespaces", func(ctx SpecContext) {
+		_, err := reconciler.Reconcile(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+
+		for _, name := range propagatedSecretNames {
+			source, err := getPropagated(ctx, name, namespace.Name)
+			Expect(err).NotTo(HaveOccurred())
+			propagated, err := getPropagated(ctx, name, target.Name)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(propagated.Labels).To(HaveKeyWithValue(Label_PropagatedFrom, namespace.Name))
+			Expect(propagated.Type).To(Equal(source.Type))
+			Expect(propagated.Data).To(Equal(source.Data))
+
+			_, err = getPropagated(ctx, name, other.Name)
+			Expect(k8serrors.IsNotFound(err)).To(BeTrue())
+		}
+
+		Expect(testClient.Get(ctx, request.NamespacedName, app)).To(Succeed())
+		Expect(meta.IsStatusConditionTrue(app.Status.Conditions, Condition_SecretsPropagated)).To(BeTrue())
+	})
+
+	It("should create the NIM PVC in the selected namespaces", func(ctx SpecContext) {
+		_, err := reconciler.Reconcile(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+
+		pvc := &corev1.PersistentVolumeClaim{}
+		Expect(testClient.Get(ctx, client.ObjectKey{Name: Name_NimPvc, Namespace: target.Name}, pvc)).To(Succeed())
+		Expect(pvc.Labels).To(HaveKeyWithValue(Label_PropagatedFrom, namespace.Name))
+		Expect(pvc.Spec.AccessModes).To(ConsistOf(corev1.ReadWriteMany))
+		Expect(pvc.Spec.Resources.Requests.Storage().Equal(defaultNimPvcSize)).To(BeTrue())
+
+		err = testClient.Get(ctx, client.ObjectKey{Name: Name_NimPvc, Namespace: other.Name}, pvc)
+		Expect(k8serrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("should keep the NIM PVC provided by the namespace", func(ctx SpecContext) {
+		provided := &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: Name_NimPvc, Namespace: target.Name},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("10Gi")},
+				},
+			},
+		}
+		Expect(testClient.Create(ctx, provided)).To(Succeed())
+
+		_, err := reconciler.Reconcile(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+
+		pvc := &corev1.PersistentVolumeClaim{}
+		Expect(testClient.Get(ctx, client.ObjectKeyFromObject(provided), pvc)).To(Succeed())
+		Expect(pvc.Labels).NotTo(HaveKey(Label_PropagatedFrom))
+		Expect(pvc.Spec.AccessModes).To(ConsistOf(corev1.ReadWriteOnce))
+		Expect(pvc.Spec.Resources.Requests.Storage().Equal(resource.MustParse("10Gi"))).To(BeTrue())
+	})
+
+	It("should update the propagated secrets when the source is rotated", func(ctx SpecContext) {
+		_, err := reconciler.Reconcile(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+		isvc := newTestInferenceService(target.Name, "nim-isvc", Name_ServingRuntime)
+		Expect(testClient.Create(ctx, isvc)).To(Succeed())
+
+		source, err := getPropagated(ctx, Name_ApiKeySecret, namespace.Name)
+		Expect(err).NotTo(HaveOccurred())
+		patch := client.MergeFrom(source.DeepCopy())
+		source.Data[Key_NgcApiKey] = []byte("my-rotated-api-key")
+		Expect(testClient.Patch(ctx, source, patch)).To(Succeed())
+
+		_, err = reconciler.Reconcile(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+
+		propagated, err := getPropagated(ctx, Name_ApiKeySecret, target.Name)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(propagated.Data).To(HaveKeyWithValue(Key_NgcApiKey, []byte("my-rotated-api-key")))
+
+		Expect(testClient.Get(ctx, client.ObjectKeyFromObject(isvc), isvc)).To(Succeed())
+		hash, _, _ := unstructured.NestedString(isvc.Object, "spec", "predictor", "annotations", Annotation_ApiKeyHash)
+		Expect(hash).To(Equal(hashApiKey("my-rotated-api-key")))
+	})
+
+	It("should delete the propagated secrets when the namespace leaves the selector", func(ctx SpecContext) {
+		_, err := reconciler.Reconcile(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+
+		patch := client.MergeFrom(target.DeepCopy())
+		delete(target.Labels, testPropagationLabel)
+		Expect(testClient.Patch(ctx, target, patch)).To(Succeed())
+
+		_, err = reconciler.Reconcile(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+
+		for _, name := range propagatedSecretNames {
+			_, err = getPropagated(ctx, name, target.Name)
+			Expect(k8serrors.IsNotFound(err)).To(BeTrue())
+		}
+		expectNimPvcDeleted(ctx, target.Name)
+	})
+
+	It("should delete the propagated secrets when the OdhNimApp is deleted", func(ctx SpecContext) {
+		_, err := reconciler.Reconcile(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(testClient.Get(ctx, request.NamespacedName, app)).To(Succeed())
+		patch := client.MergeFrom(app.DeepCopy())
+		app.Finalizers = nil
+		Expect(testClient.Patch(ctx, app, patch)).To(Succeed())
+		Expect(cleanup(ctx, app)).To(Succeed())
+
+		_, err = reconciler.Reconcile(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+
+		for _, name := range propagatedSecretNames {
+			_, err = getPropagated(ctx, name, target.Name)
+			Expect(k8serrors.IsNotFound(err)).To(BeTrue())
+		}
+		expectNimPvcDeleted(ctx, target.Name)
+	})
+
+	It("should propagate the secrets to the namespaces selected by default", func(ctx SpecContext) {
+		reconciler.DefaultSelector = &metav1.LabelSelector{
+			MatchLabels: map[string]string{testPropagationLabel: namespace.Name},
+		}
+		Expect(testClient.Get(ctx, request.NamespacedName, app)).To(Succeed())
+		patch := client.MergeFrom(app.DeepCopy())
+		app.Spec.Propagation = v1beta1.OdhNimAppSpecPropagation{}
+		Expect(testClient.Patch(ctx, app, patch)).To(Succeed())
+
+		_, err := reconciler.Reconcile(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+
+		for _, name := range propagatedSecretNames {
+			_, err = getPropagated(ctx, name, target.Name)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = getPropagated(ctx, name, other.Name)
+			Expect(k8serrors.IsNotFound(err)).To(BeTrue())
+		}
+		Expect(testClient.Get(ctx, request.NamespacedName, app)).To(Succeed())
+		Expect(meta.IsStatusConditionTrue(app.Status.Conditions, Condition_SecretsPropagated)).To(BeTrue())
+
+		By("not propagating without a default selector")
+		reconciler.DefaultSelector = nil
+		_, err = reconciler.Reconcile(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+		for _, name := range propagatedSecretNames {
+			_, err = getPropagated(ctx, name, target.Name)
+			Expect(k8serrors.IsNotFound(err)).To(BeTrue())
+		}
+	})
+
+	It("should propagate the secrets to the listed namespaces", func(ctx SpecContext) {
+		Expect(testClient.Get(ctx, request.NamespacedName, app)).To(Succeed())
+		patch := client.MergeFrom(app.DeepCopy())
+		app.Spec.Propagation.Namespaces = []string{other.Name, "propagation-missing"}
+		Expect(testClient.Patch(ctx, app, patch)).To(Succeed())
+
+		_, err := reconciler.Reconcile(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+
+		for _, name := range propagatedSecretNames {
+			_, err = getPropagated(ctx, name, target.Name)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = getPropagated(ctx, name, other.Name)
+			Expect(err).NotTo(HaveOccurred())
+		}
+	})
+
+	It("should not propagate the secrets with an empty selector", func(ctx SpecContext) {
+		Expect(testClient.Get(ctx, request.NamespacedName, app)).To(Succeed())
+		patch := client.MergeFrom(app.DeepCopy())
+		app.Spec.Propagation.NamespaceSelector = &metav1.LabelSelector{}
+		Expect(testClient.Patch(ctx, app, patch)).To(Succeed())
+
+		_, err := reconciler.Reconcile(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+
+		for _, name := range propagatedSecretNames {
+			_, err = getPropagated(ctx, name, target.Name)
+			Expect(k8serrors.IsNotFound(err)).To(BeTrue())
+			_, err = getPropagated(ctx, name, other.Name)
+			Expect(k8serrors.IsNotFound(err)).To(BeTrue())
+		}
+	})
+
+	It("should hand over the propagated secrets to an OdhNimApp created in the namespace", func(ctx SpecContext) {
+		_, err := reconciler.Reconcile(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+		isvc := newTestInferenceService(target.Name, "nim-isvc", Name_ServingRuntime)
+		Expect(testClient.Create(ctx, isvc)).To(Succeed())
+
+		// the target namespace gets its own OdhNimApp, with its own API key
+		Expect(testClient.Create(ctx, newTestApiKeySecret(target.Name, testRotatedApiKey))).To(Succeed())
+		targetApp := newTestApp(target.Name)
+		Expect(testClient.Create(ctx, targetApp)).To(Succeed())
+		targetRequest := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(targetApp)}
+		appReconciler := &AppController{testClient, testClient.Scheme(), testNgcClient, record.NewFakeRecorder(100), "", false}
+		_, err = appReconciler.Reconcile(ctx, targetRequest)
+		Expect(err).NotTo(HaveOccurred())
+
+		expectClaimed := func() {
+			for _, name := range propagatedSecretNames {
+				claimed, err := getPropagated(ctx, name, target.Name)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(claimed.Labels).NotTo(HaveKey(Label_PropagatedFrom))
+				Expect(metav1.IsControlledBy(claimed, targetApp)).To(BeTrue())
+			}
+			apiKeySecret, err := getPropagated(ctx, Name_ApiKeySecret, target.Name)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(apiKeySecret.Data).To(HaveKeyWithValue(Key_NgcApiKey, []byte(testRotatedApiKey)))
+
+			Expect(testClient.Get(ctx, client.ObjectKeyFromObject(isvc), isvc)).To(Succeed())
+			hash, _, _ := unstructured.NestedString(isvc.Object, "spec", "predictor", "annotations",
+				Annotation_ApiKeyHash)
+			Expect(hash).To(Equal(hashApiKey(testRotatedApiKey)))
+
+			pvc := &corev1.PersistentVolumeClaim{}
+			Expect(testClient.Get(ctx, client.ObjectKey{Name: Name_NimPvc, Namespace: target.Name}, pvc)).To(Succeed())
+			Expect(pvc.Labels).NotTo(HaveKey(Label_PropagatedFrom))
+			Expect(metav1.IsControlledBy(pvc, targetApp)).To(BeTrue())
+		}
+		expectClaimed()
+		Expect(testClient.Get(ctx, client.ObjectKeyFromObject(isvc), isvc)).To(Succeed())
+		resourceVersion := isvc.GetResourceVersion()
+
+		By("not propagating to the namespace anymore")
+		_, err = reconciler.Reconcile(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = appReconciler.Reconcile(ctx, targetRequest)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = reconciler.Reconcile(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+
+		expectClaimed()
+		Expect(isvc.GetResourceVersion()).To(Equal(resourceVersion))
+		Expect(testClient.Get(ctx, request.NamespacedName, app)).To(Succeed())
+		Expect(meta.IsStatusConditionTrue(app.Status.Conditions, Condition_SecretsPropagated)).To(BeTrue())
+	})
+
+	It("should delete the propagated secrets before removing the finalizer", func(ctx SpecContext) {
+		_, err := reconciler.Reconcile(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+
+		// the propagation controller is down, the app controller handles the deletion
+		Expect(testClient.Get(ctx, request.NamespacedName, app)).To(Succeed())
+		Expect(app.Finalizers).To(ContainElement(Finalizer_NimAppCleanup))
+		Expect(testClient.Delete(ctx, app)).To(Succeed())
+		appReconciler := &AppController{testClient, testClient.Scheme(), testNgcClient, record.NewFakeRecorder(100), "", false}
+		_, err = appReconciler.Reconcile(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+
+		for _, name := range propagatedSecretNames {
+			_, err = getPropagated(ctx, name, target.Name)
+			Expect(k8serrors.IsNotFound(err)).To(BeTrue())
+		}
+		Expect(k8serrors.IsNotFound(testClient.Get(ctx, request.NamespacedName, app))).To(BeTrue())
+	})
+
+	It("should not modify unmanaged secrets", func(ctx SpecContext) {
+		unmanaged := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: Name_PullSecret, Namespace: target.Name},
+			Data:       map[string][]byte{"unmanaged": []byte("true")},
+		}
+		Expect(testClient.Create(ctx, unmanaged)).To(Succeed())
+
+		_, err := reconciler.Reconcile(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+
+		propagated, err := getPropagated(ctx, Name_PullSecret, target.Name)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(propagated.Data).To(Equal(unmanaged.Data))
+		_, err = getPropagated(ctx, Name_ApiKeySecret, target.Name)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(testClient.Get(ctx, request.NamespacedName, app)).To(Succeed())
+		condition := meta.FindStatusCondition(app.Status.Conditions, Condition_SecretsPropagated)
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition.Reason).To(Equal(Reason_SecretsPropagationFailed))
+	})
+
+	It("should map secret events to the OdhNimApp", func(ctx SpecContext) {
+		source, err := getPropagated(ctx, Name_PullSecret, namespace.Name)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reconciler.mapPropagated(source)).To(ConsistOf(request))
+
+		_, err = reconciler.Reconcile(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+		propagated, err := getPropagated(ctx, Name_PullSecret, target.Name)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reconciler.mapPropagated(propagated)).To(ConsistOf(request))
+
+		apiKeySecret, err := getPropagated(ctx, "odh-nim-app-api-key", namespace.Name)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reconciler.mapPropagated(apiKeySecret)).To(BeEmpty())
+	})
+})
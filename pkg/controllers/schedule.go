@@ -0,0 +1,158 @@
+// Copyright (c) 2024 Red Hat, Inc.
+
+package controllers
+
+import (
+	"context"
+	"github.com/opendatahub-io/odh-nim-operator/api/v1beta1"
+	"github.com/robfig/cron/v3"
+	"hash/fnv"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"math/rand"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"time"
+)
+
+// defaultScheduleInterval is used when the OdhNimApp schedule sets neither an interval nor a cron expression
+const defaultScheduleInterval = 24 * time.Hour
+
+const (
+	// contentCircuitThreshold is the number of consecutive failed content updates opening the circuit breaker
+	contentCircuitThreshold = 3
+	// contentCircuitMaxDelay caps the delay of content update retries while the circuit breaker is open
+	contentCircuitMaxDelay = 6 * time.Hour
+)
+
+const (
+	// scheduleJitterDivisor bounds the jitter delaying scheduled runs to a fraction of the schedule period
+	scheduleJitterDivisor = 10
+	// maxScheduleJitter caps the jitter delaying scheduled runs
+	maxScheduleJitter = 30 * time.Minute
+	// ngcMinRetryDelay is the shortest delay of rate limited NGC requests retries
+	ngcMinRetryDelay = 10 * time.Second
+	// catalogTTLMargin is the time the shared catalog outlives the schedule jitter window by, covering the delays of
+	// the runs hitting the rate limiter
+	catalogTTLMargin = 5 * time.Minute
+)
+
+// DefaultCatalogTTL is the time the NIM catalog fetched from NGC is shared for, spanning the schedule jitter window, so
+// OdhNimApps sharing a schedule are served the catalog fetched by the first of them
+const DefaultCatalogTTL = maxScheduleJitter + catalogTTLMargin
+
+// parseSchedule is used for calculating the run following a given time, a cron expression takes precedence over the
+// interval, returns an error for invalid cron expressions
+func parseSchedule(schedule v1beta1.OdhNimAppSpecSchedule) (func(time.Time) time.Time, error) {
+	if schedule.Cron != "" {
+		cronSchedule, err := cron.ParseStandard(schedule.Cron)
+		if err != nil {
+			return nil, err
+		}
+		return cronSchedule.Next, nil
+	}
+
+	interval := defaultScheduleInterval
+	if schedule.Interval != nil && schedule.Interval.Duration > 0 {
+		interval = schedule.Interval.Duration
+	}
+	return func(last time.Time) time.Time { return last.Add(interval) }, nil
+}
+
+// nextRun is used for calculating when the run following the last one is due, a missing last run is due immediately.
+// Invalid schedules fall back to the default interval.
+func nextRun(ctx context.Context, schedule v1beta1.OdhNimAppSpecSchedule, last *metav1.Time) time.Time {
+	if last == nil {
+		return time.Time{}
+	}
+
+	next, err := parseSchedule(schedule)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "invalid schedule, using the default interval")
+		next, _ = parseSchedule(v1beta1.OdhNimAppSpecSchedule{})
+	}
+	return next(last.Time)
+}
+
+// nextAppRun is used for calculating when the OdhNimApp run following the last one is due, the scheduled run is
+// delayed by the OdhNimApp schedule jitter, so OdhNimApps sharing a schedule don't hit NGC at once
+func nextAppRun(ctx context.Context, app *v1beta1.OdhNimApp, last *metav1.Time) time.Time {
+	next := nextRun(ctx, app.Spec.Schedule, last)
+	if last == nil {
+		return next
+	}
+	return next.Add(scheduleJitter(app, next.Sub(last.Time)))
+}
+
+// scheduleJitter is used for calculating the jitter delaying the scheduled runs of an OdhNimApp, derived from its
+// namespace and name, so it's stable across reconciliations and restarts, up to a tenth of the period
+func scheduleJitter(app *v1beta1.OdhNimApp, period time.Duration) time.Duration {
+	maxJitter := period / scheduleJitterDivisor
+	if maxJitter > maxScheduleJitter {
+		maxJitter = maxScheduleJitter
+	}
+	if maxJitter <= 0 {
+		return 0
+	}
+
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(app.Namespace + "/" + app.Name))
+	return time.Duration(hash.Sum64() % uint64(maxJitter))
+}
+
+// rateLimitRetryDelay is used for calculating when rate limited NGC requests are retried, after the NGC client stops
+// holding off requests, delayed by a random jitter of up to a fifth, so throttled OdhNimApps don't retry at once
+func rateLimitRetryDelay(retryAfter time.Duration) time.Duration {
+	if retryAfter < ngcMinRetryDelay {
+		retryAfter = ngcMinRetryDelay
+	}
+	return retryAfter + time.Duration(rand.Int63n(int64(retryAfter/5)+1))
+}
+
+// contentRetryTime is used for calculating when a failed content update is retried, failures below the circuit
+// breaker threshold are retried after ngcRetryInterval, so reconciliations triggered by unrelated events don't retry
+// them back-to-back, the delay is doubled per failure from the threshold on.
+func contentRetryTime(now time.Time, failures int32) *metav1.Time {
+	if failures < contentCircuitThreshold {
+		return &metav1.Time{Time: now.Add(ngcRetryInterval)}
+	}
+	delay := contentCircuitMaxDelay
+	if shift := failures - contentCircuitThreshold + 1; shift < 16 {
+		delay = ngcRetryInterval << shift
+	}
+	if delay > contentCircuitMaxDelay {
+		delay = contentCircuitMaxDelay
+	}
+	return &metav1.Time{Time: now.Add(delay)}
+}
+
+// isContentCircuitOpen is used for checking if the circuit breaker, or an NGC rate limit, holds off content updates,
+// explicit content update requests are not held off
+func isContentCircuitOpen(app *v1beta1.OdhNimApp, now time.Time) bool {
+	return app.Status.ContentRetryTime != nil && now.Before(app.Status.ContentRetryTime.Time)
+}
+
+// nextContentUpdate is used for calculating when the next content update is due, the scheduled run is delayed while the
+// circuit breaker is open or NGC rate limits content updates
+func nextContentUpdate(ctx context.Context, app *v1beta1.OdhNimApp) time.Time {
+	next := nextAppRun(ctx, app, app.Status.LastContentUpdateTime)
+	if retry := app.Status.ContentRetryTime; retry != nil && retry.After(next) {
+		return retry.Time
+	}
+	return next
+}
+
+// requeueAfter is used for calculating the delay until the earliest of the next runs, runs already due are retried
+// after the NGC retry interval
+func requeueAfter(now time.Time, nextRuns ...time.Time) time.Duration {
+	var earliest time.Time
+	for _, next := range nextRuns {
+		if earliest.IsZero() || next.Before(earliest) {
+			earliest = next
+		}
+	}
+
+	delay := earliest.Sub(now)
+	if delay <= 0 {
+		return ngcRetryInterval
+	}
+	return delay
+}
@@ -0,0 +1,93 @@
+// Copyright (c) 2024 Red Hat, Inc.
+
+package controllers
+
+import (
+	"context"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/opendatahub-io/odh-nim-operator/api/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"time"
+)
+
+var _ = Describe("Schedule", func() {
+	lastRun := metav1.NewTime(time.Date(2024, 6, 1, 10, 30, 0, 0, time.UTC))
+
+	DescribeTable("calculating the next run",
+		func(schedule v1beta1.OdhNimAppSpecSchedule, expected time.Time) {
+			Expect(nextRun(context.Background(), schedule, &lastRun)).To(Equal(expected))
+		},
+		Entry("default interval", v1beta1.OdhNimAppSpecSchedule{}, lastRun.Add(24*time.Hour)),
+		Entry("custom interval",
+			v1beta1.OdhNimAppSpecSchedule{Interval: &metav1.Duration{Duration: 6 * time.Hour}},
+			lastRun.Add(6*time.Hour)),
+		Entry("cron expression takes precedence",
+			v1beta1.OdhNimAppSpecSchedule{Interval: &metav1.Duration{Duration: time.Hour}, Cron: "0 3 * * *"},
+			time.Date(2024, 6, 2, 3, 0, 0, 0, time.UTC)),
+		Entry("invalid cron expression falls back to the default interval",
+			v1beta1.OdhNimAppSpecSchedule{Cron: "every day"}, lastRun.Add(24*time.Hour)),
+	)
+
+	It("should run immediately if never ran", func() {
+		Expect(nextRun(context.Background(), v1beta1.OdhNimAppSpecSchedule{}, nil).IsZero()).To(BeTrue())
+	})
+
+	It("should requeue for the earliest run", func() {
+		now := lastRun.Time
+		Expect(requeueAfter(now, now.Add(2*time.Hour), now.Add(time.Hour))).To(Equal(time.Hour))
+	})
+
+	It("should retry runs already due", func() {
+		now := lastRun.Time
+		Expect(requeueAfter(now, now.Add(-time.Hour), now.Add(time.Hour))).To(Equal(ngcRetryInterval))
+	})
+
+	DescribeTable("delaying content update retries with the circuit breaker",
+		func(failures int32, expected time.Duration) {
+			retryTime := contentRetryTime(lastRun.Time, failures)
+			Expect(retryTime).NotTo(BeNil())
+			Expect(retryTime.Sub(lastRun.Time)).To(Equal(expected))
+		},
+		Entry("retried after the retry interval below the threshold", int32(contentCircuitThreshold-1),
+			ngcRetryInterval),
+		Entry("opened at the threshold", int32(contentCircuitThreshold), 2*ngcRetryInterval),
+		Entry("doubled per failure", int32(contentCircuitThreshold+2), 8*ngcRetryInterval),
+		Entry("capped", int32(contentCircuitThreshold+100), contentCircuitMaxDelay),
+	)
+
+	It("should delay the next content update while the circuit breaker is open", func() {
+		retryTime := metav1.NewTime(lastRun.Add(48 * time.Hour))
+		app := &v1beta1.OdhNimApp{Status: v1beta1.OdhNimAppStatus{LastContentUpdateTime: &lastRun}}
+		Expect(nextContentUpdate(context.Background(), app)).
+			To(Equal(lastRun.Add(24*time.Hour + scheduleJitter(app, 24*time.Hour))))
+
+		app.Status.ContentRetryTime = &retryTime
+		Expect(nextContentUpdate(context.Background(), app)).To(Equal(retryTime.Time))
+		Expect(isContentCircuitOpen(app, lastRun.Time)).To(BeTrue())
+		Expect(isContentCircuitOpen(app, retryTime.Time)).To(BeFalse())
+	})
+
+	It("should delay scheduled runs by a stable jitter per OdhNimApp", func() {
+		app := &v1beta1.OdhNimApp{ObjectMeta: metav1.ObjectMeta{Name: "my-app", Namespace: "my-namespace"}}
+		otherApp := &v1beta1.OdhNimApp{ObjectMeta: metav1.ObjectMeta{Name: "my-app", Namespace: "other-namespace"}}
+
+		jitter := scheduleJitter(app, 24*time.Hour)
+		Expect(jitter).To(BeNumerically(">=", 0))
+		Expect(jitter).To(BeNumerically("<", maxScheduleJitter))
+		Expect(scheduleJitter(app, 24*time.Hour)).To(Equal(jitter))
+		Expect(scheduleJitter(otherApp, 24*time.Hour)).NotTo(Equal(jitter))
+		Expect(scheduleJitter(app, time.Hour)).To(BeNumerically("<", time.Hour/scheduleJitterDivisor))
+
+		Expect(nextAppRun(context.Background(), app, &lastRun)).To(Equal(lastRun.Add(24*time.Hour + jitter)))
+		Expect(nextAppRun(context.Background(), app, nil).IsZero()).To(BeTrue())
+	})
+
+	It("should retry rate limited requests after the NGC client holds off requests", func() {
+		for i := 0; i < 10; i++ {
+			Expect(rateLimitRetryDelay(time.Minute)).To(BeNumerically(">=", time.Minute))
+			Expect(rateLimitRetryDelay(time.Minute)).To(BeNumerically("<=", time.Minute+time.Minute/5))
+			Expect(rateLimitRetryDelay(0)).To(BeNumerically(">=", ngcMinRetryDelay))
+		}
+	})
+})
@@ -5,46 +5,51 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"github.com/opendatahub-io/odh-nim-operator/api/v1beta1"
 	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"time"
 )
 
 type SecretController struct {
 	client.Client
-	Scheme *runtime.Scheme
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+}
+
+// nimAppLabelPredicate is used for filtering Secret events, we only watch Secrets with nim.opendatahub.io/nim-app set to
+// true, updates are also accepted if the label was set on the old object so we can handle the label removal
+var nimAppLabelPredicate = predicate.Funcs{
+	CreateFunc: func(createEvent event.CreateEvent) bool {
+		return hasNimAppLabel(createEvent.Object)
+	},
+	DeleteFunc: func(deleteEvent event.DeleteEvent) bool {
+		return hasNimAppLabel(deleteEvent.Object)
+	},
+	UpdateFunc: func(updateEvent event.UpdateEvent) bool {
+		return hasNimAppLabel(updateEvent.ObjectOld) || hasNimAppLabel(updateEvent.ObjectNew)
+	},
+	GenericFunc: func(genericEvent event.GenericEvent) bool {
+		return hasNimAppLabel(genericEvent.Object)
+	},
 }
 
 // SetupWithManager is used for setting up the controller with a manager (check the init function)
-// Note the event filtering, we only watch Secrets with nim.opendatahub.io/nim-app set to true
+// Note the event filtering, we only watch Secrets with nim.opendatahub.io/nim-app set to true (or removed)
 func (r *SecretController) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		Named("odh-nim-secret-controller").
 		For(&corev1.Secret{}).
-		WithEventFilter(predicate.Funcs{
-			CreateFunc: func(createEvent event.CreateEvent) bool {
-				value, found := createEvent.Object.GetLabels()[Label_NimApp]
-				return found && value == "true"
-			},
-			DeleteFunc: func(deleteEvent event.DeleteEvent) bool {
-				value, found := deleteEvent.Object.GetLabels()[Label_NimApp]
-				return found && value == "true"
-			},
-			UpdateFunc: func(updateEvent event.UpdateEvent) bool {
-				// TODO do we want to tear down when this labels is removed, if not, than we only need the new object
-				valueOld, foundOld := updateEvent.ObjectOld.GetLabels()[Label_NimApp]
-				valueNew, foundNew := updateEvent.ObjectOld.GetLabels()[Label_NimApp]
-				return (foundOld && valueOld == "true") || (foundNew && valueNew == "true")
-			},
-			GenericFunc: func(genericEvent event.GenericEvent) bool {
-				value, found := genericEvent.Object.GetLabels()[Label_NimApp]
-				return found && value == "true"
-			},
-		}).
+		WithEventFilter(nimAppLabelPredicate).
 		Complete(r)
 }
 
@@ -56,42 +61,162 @@ func (r *SecretController) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 	// all funcs we invoke in this context should use 'logger := log.FromContext(ctx)' to get the correct logger
 	logger.V(1).Info(fmt.Sprintf("got request for Secret %s", req.NamespacedName))
 
-	// TODO write code
-	// 1. Fetch the Secret
-	// 2. Fetch the OdhNimApp referencing this Secret by Name and Namespace (OdhNimApp.Spec.ApiKey.SecretRef)
-	// 3. Fetch the Cron Job owned by this secret
-	//
-	// 4. If Secret NOT found (deleted):
-	//		- Delete the OdhNimApp (if found)
-	//		- Delete the CronJob (if found)
-	//		- Break reconciliation
-	//
-	// # This is statement depends on comment at the UpdateFunc of the Predicates,
-	// # if we only check for the label on the new object, this IF can be removed.
-	// 5. If label "nim.opendatahub.io/nim-app" NOT "true":
-	//		- Patch OdhNimApp.Status.Condition[Type=ApiKeyValidated] to False
-	//		- Do we want to tear down anything at this point?
-	//		- Break reconciliation
-	//
-	// 6. If the OdhNimApp found:
-	//		- Patch OdhNimApp.Spec.ApiKey.Validate to True
-	//
-	// 7. Else (OdhNimApp NOT found):
-	//		- Create new OdhNimApp, set:
-	//			- OdhNimApp.Spec.ApiKey.Validate to True
-	//			- OdhNimApp.Spec.ApiKey.SecretRef to reference this Secret by Name and Namespace
-	//
-	// 8.  Reconcile a daily recurring Cron Job owned by the OdhNimApp, patching OdhNimApp.Spec.ApiKey.Validate to True
+	// only one OdhNimApp is allowed in a namespace (enforced by the admission webhook)
+	app, err := r.getNamespaceApp(ctx, req.Namespace)
+	if err != nil {
+		logger.Error(err, "failed fetching OdhNimApp")
+		return ctrl.Result{}, err
+	}
+
+	secret := &corev1.Secret{}
+	if err = r.Get(ctx, req.NamespacedName, secret); err != nil {
+		if !k8serrors.IsNotFound(err) {
+			logger.Error(err, "failed fetching Secret")
+			return ctrl.Result{}, err
+		}
+
+		// secret deleted, delete the OdhNimApp referencing it
+		if app != nil && referencesSecret(app, req.NamespacedName) {
+			if err = r.Delete(ctx, app); err != nil && !k8serrors.IsNotFound(err) {
+				logger.Error(err, "failed deleting OdhNimApp")
+				return ctrl.Result{}, err
+			}
+			logger.Info("deleted OdhNimApp", "name", app.Name)
+			r.Recorder.Eventf(app, corev1.EventTypeNormal, Reason_ApiKeySecretDeleted,
+				"the API key secret %s was deleted, deleting the OdhNimApp", req.Name)
+		}
+		return ctrl.Result{}, nil
+	}
+
+	if !hasNimAppLabel(secret) {
+		// the label was removed, invalidate the OdhNimApp referencing this secret
+		if app != nil && referencesSecret(app, req.NamespacedName) {
+			if err = r.tearDown(ctx, app); err != nil {
+				return ctrl.Result{}, err
+			}
+			r.Recorder.Eventf(secret, corev1.EventTypeWarning, Reason_ApiKeySecretUnlabeled,
+				"the secret is no longer labeled %s=true, invalidated OdhNimApp %s", Label_NimApp, app.Name)
+		}
+		return ctrl.Result{}, nil
+	}
+
+	if app == nil {
+		// request the validation and the content fetch using the annotations, the spec booleans are left for users
+		requestedAt := time.Now().UTC().Format(time.RFC3339Nano)
+		app = &v1beta1.OdhNimApp{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      Name_NimApp,
+				Namespace: secret.Namespace,
+				Annotations: map[string]string{
+					Annotation_ValidateRequestedAt: requestedAt,
+					Annotation_RefreshRequestedAt:  requestedAt,
+				},
+			},
+			Spec: v1beta1.OdhNimAppSpec{
+				ApiKey: v1beta1.OdhNimAppSpecApiKey{
+					SecretRef: &corev1.ObjectReference{Name: secret.Name, Namespace: secret.Namespace},
+				},
+			},
+		}
+		if err = r.Create(ctx, app); err != nil {
+			logger.Error(err, "failed creating OdhNimApp")
+			return ctrl.Result{}, err
+		}
+		logger.Info("created OdhNimApp", "name", app.Name)
+		r.Recorder.Eventf(secret, corev1.EventTypeNormal, Reason_NimAppCreated, "created OdhNimApp %s", app.Name)
+		return ctrl.Result{}, nil
+	}
+
+	// the secret was created or modified, point the OdhNimApp to it and request a validation, the request is made using
+	// the annotation, so the desired state is not mutated
+	if !isRequestPending(app, Annotation_ValidateRequestedAt, app.Status.ObservedValidateRequestedAt) ||
+		!referencesSecret(app, req.NamespacedName) {
+		patch := client.MergeFrom(app.DeepCopy())
+		requestedAt := time.Now().UTC().Format(time.RFC3339Nano)
+		metav1.SetMetaDataAnnotation(&app.ObjectMeta, Annotation_ValidateRequestedAt, requestedAt)
+		app.Spec.ApiKey.SecretRef = &corev1.ObjectReference{Name: secret.Name, Namespace: secret.Namespace}
+		if err = r.Patch(ctx, app, patch); err != nil {
+			logger.Error(err, "failed patching OdhNimApp")
+			return ctrl.Result{}, err
+		}
+		logger.Info("requested API key validation", "name", app.Name)
+		r.Recorder.Eventf(secret, corev1.EventTypeNormal, Reason_ValidationRequested,
+			"requested an API key validation from OdhNimApp %s", app.Name)
+	}
 
 	return ctrl.Result{}, nil
 }
 
+// tearDown is used for marking the OdhNimApp API key as not validated, and deleting the generated resources if the
+// OdhNimApp.Spec.TeardownPolicy is Delete
+func (r *SecretController) tearDown(ctx context.Context, app *v1beta1.OdhNimApp) error {
+	logger := log.FromContext(ctx)
+
+	condition := metav1.Condition{
+		Type:    Condition_ApiKeyValidated,
+		Status:  metav1.ConditionFalse,
+		Reason:  Reason_ApiKeySecretUnlabeled,
+		Message: fmt.Sprintf("the API key secret is no longer labeled %s=true", Label_NimApp),
+	}
+	recordConditionEvent(r.Recorder, app, condition)
+	if err := patchCondition(ctx, r.Client, app, condition); err != nil {
+		logger.Error(err, "failed patching validation status")
+		return err
+	}
+
+	if app.Spec.TeardownPolicy != v1beta1.TeardownPolicyDelete {
+		logger.Info("retaining generated resources", "name", app.Name)
+		return nil
+	}
+
+	if err := deleteGeneratedResources(ctx, r.Client, app); err != nil {
+		logger.Error(err, "failed deleting generated resources")
+		return err
+	}
+
+	if err := patchStatus(ctx, r.Client, app, func() {
+		app.Status.ConfigMapRef = nil
+		app.Status.TemplateRef = nil
+		app.Status.LastCatalogDiff = nil
+	}); err != nil {
+		logger.Error(err, "failed patching OdhNimApp status")
+		return err
+	}
+
+	logger.Info("deleted generated resources", "name", app.Name)
+	r.Recorder.Event(app, corev1.EventTypeNormal, Reason_GeneratedResourcesDeleted,
+		"deleted the generated resources as the teardown policy is Delete")
+	return nil
+}
+
+// getNamespaceApp is used for fetching the OdhNimApp in a namespace, returns nil if not found
+func (r *SecretController) getNamespaceApp(ctx context.Context, namespace string) (*v1beta1.OdhNimApp, error) {
+	apps := &v1beta1.OdhNimAppList{}
+	if err := r.List(ctx, apps, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	if len(apps.Items) == 0 {
+		return nil, nil
+	}
+	return &apps.Items[0], nil
+}
+
+// referencesSecret is used for checking if an OdhNimApp references a Secret for its API key
+func referencesSecret(app *v1beta1.OdhNimApp, secret types.NamespacedName) bool {
+	ref := app.Spec.ApiKey.SecretRef
+	if ref == nil || ref.Name != secret.Name {
+		return false
+	}
+	return ref.Namespace == "" || ref.Namespace == secret.Namespace
+}
+
 // init is used for registering the secret controller for loading
 func init() {
 	controllerSetups = append(controllerSetups, func(opts ControllerOptions) error {
 		return (&SecretController{
 			opts.Manager.GetClient(),
 			opts.Manager.GetScheme(),
+			opts.Manager.GetEventRecorderFor("odh-nim-secret-controller"),
 		}).SetupWithManager(opts.Manager)
 	})
 }
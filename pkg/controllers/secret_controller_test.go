@@ -0,0 +1,176 @@
+// Copyright (c) 2024 Red Hat, Inc.
+
+package controllers
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/opendatahub-io/odh-nim-operator/api/v1beta1"
+	templatev1 "github.com/openshift/api/template/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+var _ = Describe("SecretController", func() {
+	var reconciler *SecretController
+	var recorder *record.FakeRecorder
+	var namespace *corev1.Namespace
+	var secret *corev1.Secret
+	var request ctrl.Request
+	var appKey client.ObjectKey
+
+	BeforeEach(func(ctx SpecContext) {
+		recorder = record.NewFakeRecorder(100)
+		reconciler = &SecretController{testClient, testClient.Scheme(), recorder}
+
+		namespace = &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{GenerateName: "secret-controller-"}}
+		Expect(testClient.Create(ctx, namespace)).To(Succeed())
+
+		secret = newTestApiKeySecret(namespace.Name, testValidApiKey)
+		Expect(testClient.Create(ctx, secret)).To(Succeed())
+		request = ctrl.Request{NamespacedName: client.ObjectKeyFromObject(secret)}
+		appKey = client.ObjectKey{Name: Name_NimApp, Namespace: namespace.Name}
+	})
+
+	It("should create an OdhNimApp referencing a labeled secret", func(ctx SpecContext) {
+		_, err := reconciler.Reconcile(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+
+		app := &v1beta1.OdhNimApp{}
+		Expect(testClient.Get(ctx, appKey, app)).To(Succeed())
+		Expect(app.Spec.ApiKey.Validate).To(BeFalse())
+		Expect(app.Spec.Content.Update).To(BeFalse())
+		Expect(isRequestPending(app, Annotation_ValidateRequestedAt, app.Status.ObservedValidateRequestedAt)).To(BeTrue())
+		Expect(isRequestPending(app, Annotation_RefreshRequestedAt, app.Status.ObservedRefreshRequestedAt)).To(BeTrue())
+		Expect(app.Spec.ApiKey.SecretRef.Name).To(Equal(secret.Name))
+		Expect(app.Spec.ApiKey.SecretRef.Namespace).To(Equal(secret.Namespace))
+		Expect(drainEvents(recorder)).To(ConsistOf(HavePrefix("Normal " + Reason_NimAppCreated)))
+	})
+
+	It("should request a validation when the secret is modified", func(ctx SpecContext) {
+		_, err := reconciler.Reconcile(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+
+		// the validation requested on creation was handled
+		app := &v1beta1.OdhNimApp{}
+		Expect(testClient.Get(ctx, appKey, app)).To(Succeed())
+		app.Status.ObservedValidateRequestedAt = app.Annotations[Annotation_ValidateRequestedAt]
+		Expect(testClient.Status().Update(ctx, app)).To(Succeed())
+		Expect(isRequestPending(app, Annotation_ValidateRequestedAt, app.Status.ObservedValidateRequestedAt)).To(BeFalse())
+
+		_, err = reconciler.Reconcile(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(testClient.Get(ctx, appKey, app)).To(Succeed())
+		Expect(app.Spec.ApiKey.Validate).To(BeFalse())
+		Expect(isRequestPending(app, Annotation_ValidateRequestedAt, app.Status.ObservedValidateRequestedAt)).To(BeTrue())
+	})
+
+	It("should delete the OdhNimApp when the secret is deleted", func(ctx SpecContext) {
+		_, err := reconciler.Reconcile(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(testClient.Delete(ctx, secret)).To(Succeed())
+		_, err = reconciler.Reconcile(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+
+		err = testClient.Get(ctx, appKey, &v1beta1.OdhNimApp{})
+		Expect(k8serrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	When("the label is removed from the secret", func() {
+		var app *v1beta1.OdhNimApp
+
+		BeforeEach(func(ctx SpecContext) {
+			_, err := reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+
+			// let the app controller generate the resources
+			appReconciler := &AppController{testClient, testClient.Scheme(), testNgcClient, record.NewFakeRecorder(100), "", false}
+			_, err = appReconciler.Reconcile(ctx, ctrl.Request{NamespacedName: appKey})
+			Expect(err).NotTo(HaveOccurred())
+
+			app = &v1beta1.OdhNimApp{}
+			Expect(testClient.Get(ctx, appKey, app)).To(Succeed())
+			Expect(meta.IsStatusConditionTrue(app.Status.Conditions, Condition_ApiKeyValidated)).To(BeTrue())
+			Expect(app.Status.ConfigMapRef).NotTo(BeNil())
+		})
+
+		unlabel := func(ctx SpecContext) {
+			patch := client.MergeFrom(secret.DeepCopy())
+			delete(secret.Labels, Label_NimApp)
+			Expect(testClient.Patch(ctx, secret, patch)).To(Succeed())
+			_, err := reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(testClient.Get(ctx, appKey, app)).To(Succeed())
+		}
+
+		It("should invalidate the API key and retain the generated resources by default", func(ctx SpecContext) {
+			unlabel(ctx)
+
+			condition := meta.FindStatusCondition(app.Status.Conditions, Condition_ApiKeyValidated)
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal(Reason_ApiKeySecretUnlabeled))
+			Expect(app.Status.ConfigMapRef).NotTo(BeNil())
+			Expect(drainEvents(recorder)).To(ContainElement(HavePrefix("Warning " + Reason_ApiKeySecretUnlabeled)))
+
+			cmKey := client.ObjectKey{Name: Name_ContentConfigMap, Namespace: namespace.Name}
+			Expect(testClient.Get(ctx, cmKey, &corev1.ConfigMap{})).To(Succeed())
+		})
+
+		It("should invalidate the API key and delete the generated resources if requested", func(ctx SpecContext) {
+			patch := client.MergeFrom(app.DeepCopy())
+			app.Spec.TeardownPolicy = v1beta1.TeardownPolicyDelete
+			Expect(testClient.Patch(ctx, app, patch)).To(Succeed())
+
+			unlabel(ctx)
+
+			condition := meta.FindStatusCondition(app.Status.Conditions, Condition_ApiKeyValidated)
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal(Reason_ApiKeySecretUnlabeled))
+			Expect(app.Status.ConfigMapRef).To(BeNil())
+
+			cmKey := client.ObjectKey{Name: Name_ContentConfigMap, Namespace: namespace.Name}
+			Expect(k8serrors.IsNotFound(testClient.Get(ctx, cmKey, &corev1.ConfigMap{}))).To(BeTrue())
+			templateKey := client.ObjectKey{Name: Name_ServingTemplate, Namespace: namespace.Name}
+			Expect(k8serrors.IsNotFound(testClient.Get(ctx, templateKey, &templatev1.Template{}))).To(BeTrue())
+			pullSecretKey := client.ObjectKey{Name: Name_PullSecret, Namespace: namespace.Name}
+			Expect(k8serrors.IsNotFound(testClient.Get(ctx, pullSecretKey, &corev1.Secret{}))).To(BeTrue())
+		})
+
+		It("should request a validation when the label is set back", func(ctx SpecContext) {
+			unlabel(ctx)
+
+			patch := client.MergeFrom(secret.DeepCopy())
+			secret.SetLabels(map[string]string{Label_NimApp: "true"})
+			Expect(testClient.Patch(ctx, secret, patch)).To(Succeed())
+			_, err := reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(testClient.Get(ctx, appKey, app)).To(Succeed())
+			Expect(isRequestPending(app, Annotation_ValidateRequestedAt, app.Status.ObservedValidateRequestedAt)).
+				To(BeTrue())
+		})
+	})
+})
+
+var _ = DescribeTable("Secret event filtering",
+	func(oldLabels, newLabels map[string]string, expected bool) {
+		oldSecret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Labels: oldLabels}}
+		newSecret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Labels: newLabels}}
+
+		Expect(nimAppLabelPredicate.Update(event.UpdateEvent{ObjectOld: oldSecret, ObjectNew: newSecret})).
+			To(Equal(expected))
+	},
+	Entry("label kept", map[string]string{Label_NimApp: "true"}, map[string]string{Label_NimApp: "true"}, true),
+	Entry("label added", map[string]string{}, map[string]string{Label_NimApp: "true"}, true),
+	Entry("label removed", map[string]string{Label_NimApp: "true"}, map[string]string{}, true),
+	Entry("label set to false", map[string]string{Label_NimApp: "true"}, map[string]string{Label_NimApp: "false"}, true),
+	Entry("label never set", map[string]string{}, map[string]string{}, false),
+)
@@ -0,0 +1,114 @@
+// Copyright (c) 2024 Red Hat, Inc.
+
+package controllers
+
+// This file hosts the rendering of the NIM serving Template. The Template holds a KServe ServingRuntime used by the ODH
+// Dashboard for deploying NIM models, the runtime pulls images using the pull Secret, authenticates with NGC using the
+// API key Secret, and caches the models in the NIM PVC. The Secrets and the NIM PVC are propagated by the
+// PropagationController to the namespaces the ServingRuntime is deployed in. The ServingRuntime is built as an
+// unstructured object, so we don't need the KServe API as a dependency.
+
+import (
+	"encoding/json"
+	templatev1 "github.com/openshift/api/template/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	servingRuntimeApiVersion = "serving.kserve.io/v1alpha1"
+	servingRuntimeKind       = "ServingRuntime"
+	servingRuntimePort       = int64(8000)
+	servingCachePath         = "/mnt/models/cache"
+)
+
+// renderServingTemplate is used for rendering the desired state of the NIM serving Template into the template object,
+// only the keys owned by the operator are set, keys set by others, i.e. the server, are kept
+func renderServingTemplate(template *templatev1.Template) error {
+	if template.Labels == nil {
+		template.Labels = map[string]string{}
+	}
+	template.Labels[Label_OdhDashboard] = "true"
+
+	if template.Annotations == nil {
+		template.Annotations = map[string]string{}
+	}
+	template.Annotations["openshift.io/display-name"] = "NVIDIA NIM"
+	template.Annotations["opendatahub.io/apiProtocol"] = "REST"
+	template.Annotations["opendatahub.io/modelServingSupport"] = `["single"]`
+
+	// objects are set in their raw form, the object form is not used when encoding the template
+	runtimeRaw, err := json.Marshal(newServingRuntime())
+	if err != nil {
+		return err
+	}
+	template.Objects = []runtime.RawExtension{{Raw: runtimeRaw}}
+	return nil
+}
+
+// newServingRuntime is used for building the NIM KServe ServingRuntime included in the serving Template
+func newServingRuntime() *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": servingRuntimeApiVersion,
+		"kind":       servingRuntimeKind,
+		"metadata": map[string]any{
+			"name": Name_ServingRuntime,
+			"annotations": map[string]any{
+				"openshift.io/display-name":               "NVIDIA NIM",
+				"opendatahub.io/recommended-accelerators": `["nvidia.com/gpu"]`,
+			},
+			"labels": map[string]any{
+				Label_OdhDashboard: "true",
+			},
+		},
+		"spec": map[string]any{
+			"annotations": map[string]any{
+				"prometheus.io/path": "/metrics",
+				"prometheus.io/port": "8000",
+			},
+			"containers": []any{
+				map[string]any{
+					"name": "kserve-container",
+					"env": []any{
+						map[string]any{"name": "NIM_CACHE_PATH", "value": servingCachePath},
+						map[string]any{
+							"name": Key_NgcApiKey,
+							"valueFrom": map[string]any{
+								"secretKeyRef": map[string]any{"name": Name_ApiKeySecret, "key": Key_NgcApiKey},
+							},
+						},
+					},
+					"ports": []any{
+						map[string]any{"containerPort": servingRuntimePort, "protocol": "TCP"},
+					},
+					"resources": map[string]any{
+						"limits":   map[string]any{"cpu": "2", "memory": "8Gi"},
+						"requests": map[string]any{"cpu": "1", "memory": "4Gi"},
+					},
+					"volumeMounts": []any{
+						map[string]any{"name": "shm", "mountPath": "/dev/shm"},
+						map[string]any{"name": Name_NimPvc, "mountPath": servingCachePath},
+					},
+				},
+			},
+			"imagePullSecrets": []any{
+				map[string]any{"name": Name_PullSecret},
+			},
+			"multiModel":       false,
+			"protocolVersions": []any{"grpc-v2", "v2"},
+			"supportedModelFormats": []any{
+				map[string]any{"name": "nvidia-nim", "version": "1.0.0", "autoSelect": true},
+			},
+			"volumes": []any{
+				map[string]any{
+					"name":     "shm",
+					"emptyDir": map[string]any{"medium": "Memory", "sizeLimit": "2Gi"},
+				},
+				map[string]any{
+					"name":                  Name_NimPvc,
+					"persistentVolumeClaim": map[string]any{"claimName": Name_NimPvc},
+				},
+			},
+		},
+	}}
+}
@@ -3,10 +3,15 @@
 package controllers
 
 import (
+	"encoding/json"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/opendatahub-io/odh-nim-operator/pkg/ngc"
 	"github.com/opendatahub-io/odh-nim-operator/pkg/utils"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"net/http"
+	"net/http/httptest"
 	"path/filepath"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -15,10 +20,21 @@ import (
 	"testing"
 )
 
+// the api keys accepted and the model served by the ngc stand-in server
+const (
+	testValidApiKey   = "my-valid-api-key"
+	testRotatedApiKey = "my-rotated-api-key"
+	testModelName     = "llama3-8b-instruct"
+)
+
 // use the test client for testing the controllers
 var testClient client.Client
 var testEnv *envtest.Environment
 
+// use the ngc stand-in server and the ngc client pointing at it for testing the controllers
+var testNgcServer *httptest.Server
+var testNgcClient *ngc.Client
+
 func TestControllers(t *testing.T) {
 	RegisterFailHandler(Fail)
 	RunSpecs(t, "Controller Tests")
@@ -29,7 +45,11 @@ var _ = BeforeSuite(func(ctx SpecContext) {
 
 	By("bootstrapping testing environment")
 	testEnv = &envtest.Environment{
-		CRDDirectoryPaths: []string{filepath.Join("testdata", "required_crds")},
+		CRDDirectoryPaths: []string{
+			filepath.Join("..", "..", "config", "crd"),
+			filepath.Join("testdata", "required_crds"),
+		},
+		ErrorIfCRDPathMissing: true,
 	}
 
 	// install the scheme
@@ -45,10 +65,16 @@ var _ = BeforeSuite(func(ctx SpecContext) {
 	testClient, err = client.New(cfg, client.Options{Scheme: scheme})
 	Expect(err).NotTo(HaveOccurred())
 	Expect(testClient).NotTo(BeNil())
+
+	// start the ngc stand-in server and create a client for it
+	testNgcServer = httptest.NewServer(newTestNgcHandler())
+	testNgcClient, err = ngc.NewClient(ngc.ClientOptions{AuthUrl: testNgcServer.URL, ApiUrl: testNgcServer.URL})
+	Expect(err).NotTo(HaveOccurred())
 })
 
 var _ = AfterSuite(func() {
 	By("tearing down testing environment")
+	testNgcServer.Close()
 	Expect(testEnv.Stop()).To(Succeed())
 })
 
@@ -63,3 +89,46 @@ func cleanup(ctx SpecContext, objs ...client.Object) error {
 	}
 	return nil
 }
+
+// drainEvents is used for receiving the events recorded so far by a fake recorder
+func drainEvents(recorder *record.FakeRecorder) []string {
+	var events []string
+	for {
+		select {
+		case event := <-recorder.Events:
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+// newTestNgcHandler is used for creating a handler standing in for NGC, only testValidApiKey and testRotatedApiKey are
+// accepted and the catalog holds one model named testModelName
+func newTestNgcHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if _, apiKey, ok := r.BasicAuth(); !ok || (apiKey != testValidApiKey && apiKey != testRotatedApiKey) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"token": "my-token", "expires_in": 300})
+	})
+	mux.HandleFunc("/v2/search/catalog/resources/CONTAINER", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"resultPageTotal": 1,
+			"results": []any{map[string]any{
+				"resources": []any{map[string]string{"resourceId": "nim/meta/" + testModelName}},
+			}},
+		})
+	})
+	mux.HandleFunc("/v2/org/nim/team/meta/repos/"+testModelName, func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"name":      testModelName,
+			"namespace": "nim/meta",
+			"tags":      []string{"1.0.0"},
+			"latestTag": "1.0.0",
+		})
+	})
+	return mux
+}
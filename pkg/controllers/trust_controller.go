@@ -0,0 +1,149 @@
+// Copyright (c) 2024 Red Hat, Inc.
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"github.com/opendatahub-io/odh-nim-operator/pkg/ngc"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+	"strings"
+)
+
+var proxyGvk = schema.GroupVersionKind{
+	Group:   "config.openshift.io",
+	Version: "v1",
+	Kind:    "Proxy",
+}
+
+// TrustController is used for reloading the NGC client transport when the cluster-wide Proxy or the trusted CA bundle
+// ConfigMap change, the Proxy is handled as an unstructured object, so we don't need the OpenShift config API as a
+// dependency. The trusted CA bundle ConfigMap is labeled config.openshift.io/inject-trusted-cabundle, so the cluster
+// injects the CA bundle of the cluster-wide Proxy, proxies with TLS interception included.
+type TrustController struct {
+	client.Client
+	NgcClient   *ngc.Client
+	CaBundleKey types.NamespacedName
+}
+
+// SetupWithManager is used for setting up the controller with a manager (check the init function)
+// Note the Proxy watch, it's only added on clusters serving the OpenShift config API, and the channel source, it loads
+// the transport options when the controller starts. All events are mapped to a single request for the CA bundle key.
+func (r *TrustController) SetupWithManager(mgr ctrl.Manager) error {
+	trustRequest := func(client.Object) []reconcile.Request {
+		return []reconcile.Request{{NamespacedName: r.CaBundleKey}}
+	}
+
+	start := make(chan event.GenericEvent, 1)
+	start <- event.GenericEvent{Object: &corev1.ConfigMap{}}
+
+	bldr := ctrl.NewControllerManagedBy(mgr).
+		Named("odh-nim-trust-controller").
+		For(&corev1.ConfigMap{}, builder.WithPredicates(predicate.NewPredicateFuncs(func(obj client.Object) bool {
+			return client.ObjectKeyFromObject(obj) == r.CaBundleKey
+		}))).
+		Watches(&source.Channel{Source: start}, handler.EnqueueRequestsFromMapFunc(trustRequest))
+
+	if _, err := mgr.GetRESTMapper().RESTMapping(proxyGvk.GroupKind(), proxyGvk.Version); err == nil {
+		proxy := &unstructured.Unstructured{}
+		proxy.SetGroupVersionKind(proxyGvk)
+		bldr = bldr.Watches(&source.Kind{Type: proxy}, handler.EnqueueRequestsFromMapFunc(trustRequest))
+	} else if !meta.IsNoMatchError(err) {
+		return err
+	}
+
+	return bldr.Complete(r)
+}
+
+// rbac markers are in controllers.go
+
+func (r *TrustController) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx).WithName("trust-controller")
+	ctx = log.IntoContext(ctx, logger)
+	// all funcs we invoke in this context should use 'logger := log.FromContext(ctx)' to get the correct logger
+	logger.V(1).Info(fmt.Sprintf("got request for trusted CA bundle %s", req.NamespacedName))
+
+	transportOpts, err := r.getTransportOptions(ctx)
+	if err != nil {
+		logger.Error(err, "failed fetching NGC transport options")
+		return ctrl.Result{}, err
+	}
+
+	reloaded, err := r.NgcClient.Reload(transportOpts)
+	if err != nil {
+		logger.Error(err, "failed reloading NGC transport")
+		return ctrl.Result{}, err
+	}
+	if reloaded {
+		logger.Info("reloaded NGC transport", "httpProxy", transportOpts.HttpProxy,
+			"httpsProxy", transportOpts.HttpsProxy, "noProxy", transportOpts.NoProxy,
+			"caBundle", len(transportOpts.CaBundle) > 0)
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// getTransportOptions is used for reading the NGC transport options from the status of the cluster-wide Proxy, which
+// holds the effective proxy configuration, and from the trusted CA bundle ConfigMap. A missing Proxy or ConfigMap, or
+// clusters without the OpenShift config API, leave the respective options empty.
+func (r *TrustController) getTransportOptions(ctx context.Context) (ngc.TransportOptions, error) {
+	transportOpts := ngc.TransportOptions{}
+
+	proxy := &unstructured.Unstructured{}
+	proxy.SetGroupVersionKind(proxyGvk)
+	if err := r.Get(ctx, client.ObjectKey{Name: Name_ClusterProxy}, proxy); err == nil {
+		transportOpts.HttpProxy, _, _ = unstructured.NestedString(proxy.Object, "status", "httpProxy")
+		transportOpts.HttpsProxy, _, _ = unstructured.NestedString(proxy.Object, "status", "httpsProxy")
+		transportOpts.NoProxy, _, _ = unstructured.NestedString(proxy.Object, "status", "noProxy")
+	} else if !k8serrors.IsNotFound(err) && !meta.IsNoMatchError(err) {
+		return transportOpts, err
+	}
+
+	if r.CaBundleKey.Name == "" {
+		return transportOpts, nil
+	}
+	cm := &corev1.ConfigMap{}
+	if err := r.Get(ctx, r.CaBundleKey, cm); err == nil {
+		// the bundle is empty until injected
+		if caBundle := cm.Data[Key_CaBundle]; caBundle != "" {
+			transportOpts.CaBundle = []byte(caBundle)
+		}
+	} else if !k8serrors.IsNotFound(err) {
+		return transportOpts, err
+	}
+
+	return transportOpts, nil
+}
+
+// init is used for registering the odh-nim-trust controller for loading, the trusted CA bundle is optional
+func init() {
+	controllerSetups = append(controllerSetups, func(opts ControllerOptions) error {
+		caBundleKey := types.NamespacedName{}
+		if opts.TrustedCaBundle != "" {
+			namespace, name, found := strings.Cut(opts.TrustedCaBundle, "/")
+			if !found || namespace == "" || name == "" {
+				return fmt.Errorf("the trusted CA bundle %s is not in the <namespace>/<name> form", opts.TrustedCaBundle)
+			}
+			caBundleKey = types.NamespacedName{Namespace: namespace, Name: name}
+		}
+		return (&TrustController{
+			opts.Manager.GetClient(),
+			opts.NgcClient,
+			caBundleKey,
+		}).SetupWithManager(opts.Manager)
+	})
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2024 Red Hat, Inc.
+
+package controllers
+
+import (
+	"encoding/json"
+	"encoding/pem"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/opendatahub-io/odh-nim-operator/pkg/ngc"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"net/http"
+	"net/http/httptest"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("TrustController", func() {
+	var reconciler *TrustController
+	var request ctrl.Request
+	var proxied []string
+	var proxyServer *httptest.Server
+
+	BeforeEach(func(ctx SpecContext) {
+		namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{GenerateName: "trust-controller-"}}
+		Expect(testClient.Create(ctx, namespace)).To(Succeed())
+
+		proxied = nil
+		proxyServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			proxied = append(proxied, r.URL.String())
+			_ = json.NewEncoder(w).Encode(map[string]any{"token": "my-token"})
+		}))
+		DeferCleanup(proxyServer.Close)
+
+		ngcClient, err := ngc.NewClient(ngc.ClientOptions{AuthUrl: "http://authn.ngc.internal"})
+		Expect(err).NotTo(HaveOccurred())
+
+		key := types.NamespacedName{Name: "trusted-ca-bundle", Namespace: namespace.Name}
+		reconciler = &TrustController{testClient, ngcClient, key}
+		request = ctrl.Request{NamespacedName: key}
+	})
+
+	It("should send NGC requests through the cluster-wide proxy", func(ctx SpecContext) {
+		proxy := &unstructured.Unstructured{}
+		proxy.SetGroupVersionKind(proxyGvk)
+		proxy.SetName(Name_ClusterProxy)
+		Expect(unstructured.SetNestedMap(proxy.Object, map[string]any{}, "spec")).To(Succeed())
+		Expect(testClient.Create(ctx, proxy)).To(Succeed())
+		DeferCleanup(func(ctx SpecContext) {
+			Expect(testClient.Delete(ctx, proxy)).To(Succeed())
+		})
+		Expect(unstructured.SetNestedMap(proxy.Object, map[string]any{
+			"httpProxy": proxyServer.URL,
+			"noProxy":   "localhost,.cluster.local",
+		}, "status")).To(Succeed())
+		Expect(testClient.Status().Update(ctx, proxy)).To(Succeed())
+
+		_, err := reconciler.Reconcile(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(reconciler.NgcClient.ValidateApiKey(ctx, "my-api-key")).To(Succeed())
+		Expect(proxied).To(ConsistOf("http://authn.ngc.internal/token?service=ngc"))
+	})
+
+	It("should trust the injected CA bundle", func(ctx SpecContext) {
+		tlsServer := httptest.NewTLSServer(proxyServer.Config.Handler)
+		DeferCleanup(tlsServer.Close)
+		ngcClient, err := ngc.NewClient(ngc.ClientOptions{AuthUrl: tlsServer.URL})
+		Expect(err).NotTo(HaveOccurred())
+		reconciler.NgcClient = ngcClient
+
+		// the bundle is empty until injected
+		cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+			Name:      request.Name,
+			Namespace: request.Namespace,
+			Labels:    map[string]string{"config.openshift.io/inject-trusted-cabundle": "true"},
+		}}
+		Expect(testClient.Create(ctx, cm)).To(Succeed())
+		_, err = reconciler.Reconcile(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ngcClient.ValidateApiKey(ctx, "my-api-key")).To(MatchError(ngc.ErrUnreachable))
+
+		patch := client.MergeFrom(cm.DeepCopy())
+		cm.Data = map[string]string{
+			Key_CaBundle: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: tlsServer.Certificate().Raw})),
+		}
+		Expect(testClient.Patch(ctx, cm, patch)).To(Succeed())
+		_, err = reconciler.Reconcile(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ngcClient.ValidateApiKey(ctx, "my-api-key")).To(Succeed())
+	})
+})
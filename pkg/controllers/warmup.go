@@ -0,0 +1,112 @@
+// Copyright (c) 2024 Red Hat, Inc.
+
+package controllers
+
+// This file hosts the rendering of the model cache warm-up Jobs. A Job is created per model and catalog version, it
+// runs the NIM container download-to-cache command, caching the model in the NIM PVC shared with the NIM
+// ServingRuntime pods (check serving_template.go and nim_pvc.go). Jobs are immutable, a new catalog version gets a
+// new Job.
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/opendatahub-io/odh-nim-operator/api/v1beta1"
+	"github.com/opendatahub-io/odh-nim-operator/pkg/ngc"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"regexp"
+	"strings"
+)
+
+const (
+	warmupJobPrefix    = "nim-warmup-"
+	warmupCommand      = "download-to-cache"
+	warmupBackoffLimit = int32(2)
+)
+
+// invalidJobNameChars is used for replacing the characters of model names not allowed in Job names
+var invalidJobNameChars = regexp.MustCompile("[^a-z0-9-]+")
+
+// warmupJobName is used for naming the warm-up Job of a model version, the name is suffixed with a hash of the model
+// and version, so truncated names don't collide
+func warmupJobName(model, version string) string {
+	sum := sha256.Sum256([]byte(model + ":" + version))
+	suffix := hex.EncodeToString(sum[:])[:8]
+
+	name := strings.Trim(invalidJobNameChars.ReplaceAllString(strings.ToLower(model), "-"), "-")
+	maxLength := validation.DNS1123LabelMaxLength - len(warmupJobPrefix) - len(suffix) - 1
+	if len(name) > maxLength {
+		name = strings.TrimRight(name[:maxLength], "-")
+	}
+	return fmt.Sprintf("%s%s-%s", warmupJobPrefix, name, suffix)
+}
+
+// newWarmupJob is used for building the warm-up Job caching the model version in the NIM PVC
+func newWarmupJob(namespace string, model ngc.Model, version string) *batchv1.Job {
+	backoffLimit := warmupBackoffLimit
+	labels := map[string]string{Label_Warmup: "true"}
+
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      warmupJobName(model.Name, version),
+			Namespace: namespace,
+			Labels:    labels,
+			Annotations: map[string]string{
+				Annotation_WarmupModel:   model.Name,
+				Annotation_WarmupVersion: version,
+			},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{
+					RestartPolicy:    corev1.RestartPolicyNever,
+					ImagePullSecrets: []corev1.LocalObjectReference{{Name: Name_PullSecret}},
+					Containers: []corev1.Container{{
+						Name:    "warmup",
+						Image:   fmt.Sprintf("%s:%s", model.Image, version),
+						Command: []string{warmupCommand},
+						Env: []corev1.EnvVar{
+							{Name: "NIM_CACHE_PATH", Value: servingCachePath},
+							{
+								Name: Key_NgcApiKey,
+								ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+									LocalObjectReference: corev1.LocalObjectReference{Name: Name_ApiKeySecret},
+									Key:                  Key_NgcApiKey,
+								}},
+							},
+						},
+						VolumeMounts: []corev1.VolumeMount{{Name: Name_NimPvc, MountPath: servingCachePath}},
+					}},
+					Volumes: []corev1.Volume{{
+						Name: Name_NimPvc,
+						VolumeSource: corev1.VolumeSource{
+							PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: Name_NimPvc},
+						},
+					}},
+				},
+			},
+		},
+	}
+}
+
+// warmupJobStatus is used for reading the warm-up phase from the Job status, returns the completion time for
+// succeeded Jobs and the failure message for failed ones
+func warmupJobStatus(job *batchv1.Job) (v1beta1.WarmupPhase, *metav1.Time, string) {
+	for _, condition := range job.Status.Conditions {
+		if condition.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch condition.Type {
+		case batchv1.JobComplete:
+			return v1beta1.WarmupPhaseSucceeded, job.Status.CompletionTime, ""
+		case batchv1.JobFailed:
+			return v1beta1.WarmupPhaseFailed, nil, condition.Message
+		}
+	}
+	return v1beta1.WarmupPhaseRunning, nil, ""
+}
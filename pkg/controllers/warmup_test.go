@@ -0,0 +1,27 @@
+// Copyright (c) 2024 Red Hat, Inc.
+
+package controllers
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"strings"
+)
+
+var _ = Describe("Warmup", func() {
+	DescribeTable("naming the warm-up job",
+		func(model string, expectedPrefix string) {
+			name := warmupJobName(model, "1.0.0")
+			Expect(name).To(HavePrefix(expectedPrefix))
+			Expect(validation.IsDNS1123Label(name)).To(BeEmpty())
+		},
+		Entry("valid model name", testModelName, "nim-warmup-"+testModelName+"-"),
+		Entry("model name with dots", "llama-3.1-8b-instruct", "nim-warmup-llama-3-1-8b-instruct-"),
+		Entry("long model name", strings.Repeat("a", 80), "nim-warmup-aaaa"),
+	)
+
+	It("should name new versions differently", func() {
+		Expect(warmupJobName(testModelName, "1.0.0")).NotTo(Equal(warmupJobName(testModelName, "1.1.0")))
+	})
+})
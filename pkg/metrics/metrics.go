@@ -0,0 +1,226 @@
+// Copyright (c) 2024 Red Hat, Inc.
+
+package metrics
+
+// This file hosts the NIM specific metrics, registered with the controller-runtime registry served on the metric
+// address. Per OdhNimApp series are labeled with the OdhNimApp namespace and name, and deleted with the OdhNimApp. The
+// labels are prefixed with app_, as the namespace label is set to the operator namespace by Prometheus on scrape.
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sync"
+	"time"
+)
+
+const metricsNamespace = "odh_nim"
+
+var appLabels = []string{"app_namespace", "app_name"}
+
+var (
+	apiKeyValidations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "api_key_validations_total",
+		Help:      "Number of API key validations per OdhNimApp by result.",
+	}, []string{"app_namespace", "app_name", "result"})
+
+	apiKeyValid = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "api_key_valid",
+		Help:      "Whether the API key of the OdhNimApp was not rejected by its last validation.",
+	}, appLabels)
+
+	apiKeyValidationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "api_key_validation_duration_seconds",
+		Help:      "Duration of API key validations per OdhNimApp.",
+		Buckets:   prometheus.DefBuckets,
+	}, appLabels)
+
+	ngcRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "ngc_requests_total",
+		Help:      "Number of requests sent to NGC by method and status code.",
+	}, []string{"method", "code"})
+
+	catalogCacheLookups = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "catalog_cache_lookups_total",
+		Help:      "Number of catalog lookups in the shared catalog cache by result.",
+	}, []string{"result"})
+
+	catalogRefreshDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "catalog_refresh_duration_seconds",
+		Help:      "Duration of NGC catalog refreshes per OdhNimApp by result.",
+		Buckets:   prometheus.ExponentialBuckets(0.5, 2, 10),
+	}, []string{"app_namespace", "app_name", "result"})
+
+	contentModels = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "content_models",
+		Help:      "Number of models in the content ConfigMap per OdhNimApp.",
+	}, appLabels)
+
+	contentImages = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "content_images",
+		Help:      "Number of distinct images in the content ConfigMap per OdhNimApp.",
+	}, appLabels)
+
+	contentNextUpdate = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "content_next_update_timestamp_seconds",
+		Help:      "Unix time the next scheduled content update per OdhNimApp is due at.",
+	}, appLabels)
+
+	appDeletion = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "app_deletion_timestamp_seconds",
+		Help:      "Unix time the deletion of the OdhNimApp was requested at, reported until the OdhNimApp is removed.",
+	}, appLabels)
+
+	contentAge = &contentAgeCollector{
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName(metricsNamespace, "", "content_last_update_age_seconds"),
+			"Seconds since the last successful content update per OdhNimApp.",
+			appLabels, nil),
+		updates: map[appKey]time.Time{},
+		now:     time.Now,
+	}
+)
+
+const (
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+
+	CacheHit  = "hit"
+	CacheMiss = "miss"
+)
+
+// ObserveApiKeyValidation is used for recording an API key validation of an OdhNimApp, the result is the reason of the
+// validation condition
+func ObserveApiKeyValidation(namespace, name, result string, duration time.Duration) {
+	apiKeyValidations.WithLabelValues(namespace, name, result).Inc()
+	apiKeyValidationDuration.WithLabelValues(namespace, name).Observe(duration.Seconds())
+}
+
+// SetApiKeyValid is used for recording whether the API key of an OdhNimApp was not rejected by its last validation
+func SetApiKeyValid(namespace, name string, valid bool) {
+	value := 0.0
+	if valid {
+		value = 1
+	}
+	apiKeyValid.WithLabelValues(namespace, name).Set(value)
+}
+
+// DeleteApiKeyValid is used for deleting the API key validity series of an OdhNimApp whose API key validation is
+// skipped
+func DeleteApiKeyValid(namespace, name string) {
+	apiKeyValid.DeleteLabelValues(namespace, name)
+}
+
+// ObserveCatalogRefresh is used for recording an NGC catalog refresh of an OdhNimApp, the result is either
+// ResultSuccess or ResultFailure
+func ObserveCatalogRefresh(namespace, name, result string, duration time.Duration) {
+	catalogRefreshDuration.WithLabelValues(namespace, name, result).Observe(duration.Seconds())
+}
+
+// ObserveCatalogCacheLookup is used for recording a lookup in the shared catalog cache, the result is either CacheHit
+// or CacheMiss
+func ObserveCatalogCacheLookup(result string) {
+	catalogCacheLookups.WithLabelValues(result).Inc()
+}
+
+// SetContent is used for recording the content ConfigMap of an OdhNimApp, the time it was last updated, and the time
+// the next scheduled update is due at
+func SetContent(namespace, name string, models, images int, lastUpdate, nextUpdate time.Time) {
+	contentModels.WithLabelValues(namespace, name).Set(float64(models))
+	contentImages.WithLabelValues(namespace, name).Set(float64(images))
+	contentNextUpdate.WithLabelValues(namespace, name).Set(float64(nextUpdate.Unix()))
+	contentAge.set(appKey{namespace, name}, lastUpdate)
+}
+
+// SetDeleting is used for recording the time the deletion of an OdhNimApp was requested at, the series of the
+// OdhNimApp are otherwise deleted as it's no longer reconciled
+func SetDeleting(namespace, name string, requested time.Time) {
+	DeleteApp(namespace, name)
+	appDeletion.WithLabelValues(namespace, name).Set(float64(requested.Unix()))
+}
+
+// DeleteApp is used for deleting the series of a deleted OdhNimApp
+func DeleteApp(namespace, name string) {
+	selector := prometheus.Labels{"app_namespace": namespace, "app_name": name}
+	apiKeyValidations.DeletePartialMatch(selector)
+	apiKeyValid.DeletePartialMatch(selector)
+	apiKeyValidationDuration.DeletePartialMatch(selector)
+	catalogRefreshDuration.DeletePartialMatch(selector)
+	contentModels.DeletePartialMatch(selector)
+	contentImages.DeletePartialMatch(selector)
+	contentNextUpdate.DeletePartialMatch(selector)
+	appDeletion.DeletePartialMatch(selector)
+	contentAge.delete(appKey{namespace, name})
+}
+
+// InstrumentNgcTransport is used for counting the requests sent to NGC through the transport
+func InstrumentNgcTransport(transport http.RoundTripper) http.RoundTripper {
+	return promhttp.InstrumentRoundTripperCounter(ngcRequests, transport)
+}
+
+// appKey is used for keying the OdhNimApp series
+type appKey struct {
+	namespace, name string
+}
+
+// contentAgeCollector is used for reporting the seconds since the last successful content update, computed on scrape
+type contentAgeCollector struct {
+	mu      sync.Mutex
+	desc    *prometheus.Desc
+	updates map[appKey]time.Time
+	now     func() time.Time
+}
+
+func (c *contentAgeCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *contentAgeCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	for key, updated := range c.updates {
+		ch <- prometheus.MustNewConstMetric(
+			c.desc, prometheus.GaugeValue, now.Sub(updated).Seconds(), key.namespace, key.name)
+	}
+}
+
+func (c *contentAgeCollector) set(key appKey, updated time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.updates[key] = updated
+}
+
+func (c *contentAgeCollector) delete(key appKey) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.updates, key)
+}
+
+// init is used for registering the NIM metrics with the controller-runtime registry
+func init() {
+	metrics.Registry.MustRegister(
+		apiKeyValidations,
+		apiKeyValid,
+		apiKeyValidationDuration,
+		ngcRequests,
+		catalogCacheLookups,
+		catalogRefreshDuration,
+		contentModels,
+		contentImages,
+		contentNextUpdate,
+		appDeletion,
+		contentAge,
+	)
+}
@@ -0,0 +1,159 @@
+// Copyright (c) 2024 Red Hat, Inc.
+
+package metrics
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"net/http"
+	"net/http/httptest"
+	"time"
+)
+
+// collect is used for collecting the series of a collector
+func collect(collector prometheus.Collector) []*dto.Metric {
+	ch := make(chan prometheus.Metric, 100)
+	collector.Collect(ch)
+	close(ch)
+
+	var series []*dto.Metric
+	for metric := range ch {
+		written := &dto.Metric{}
+		Expect(metric.Write(written)).To(Succeed())
+		series = append(series, written)
+	}
+	return series
+}
+
+// value is used for reading the value of a counter or gauge
+func value(metric prometheus.Metric) float64 {
+	written := &dto.Metric{}
+	Expect(metric.Write(written)).To(Succeed())
+	if written.Counter != nil {
+		return written.Counter.GetValue()
+	}
+	return written.Gauge.GetValue()
+}
+
+var _ = Describe("Metrics", func() {
+	const namespace, name = "metrics-test", "odh-nim-app"
+
+	AfterEach(func() {
+		DeleteApp(namespace, name)
+		contentAge.now = time.Now
+	})
+
+	It("should record API key validations by result", func() {
+		ObserveApiKeyValidation(namespace, name, "ApiKeyValidatedSuccessfully", time.Second)
+		ObserveApiKeyValidation(namespace, name, "ApiKeyValidatedSuccessfully", time.Second)
+		ObserveApiKeyValidation(namespace, name, "ApiKeyInvalid", time.Second)
+
+		Expect(value(apiKeyValidations.WithLabelValues(namespace, name, "ApiKeyValidatedSuccessfully"))).
+			To(Equal(2.0))
+		Expect(value(apiKeyValidations.WithLabelValues(namespace, name, "ApiKeyInvalid"))).To(Equal(1.0))
+		Expect(collect(apiKeyValidationDuration)).To(HaveLen(1))
+	})
+
+	It("should report whether the API key is valid", func() {
+		SetApiKeyValid(namespace, name, true)
+		Expect(value(apiKeyValid.WithLabelValues(namespace, name))).To(Equal(1.0))
+		SetApiKeyValid(namespace, name, false)
+		Expect(value(apiKeyValid.WithLabelValues(namespace, name))).To(Equal(0.0))
+	})
+
+	It("should only report the deletion timestamp of a deleting OdhNimApp", func() {
+		requested := time.Now()
+		SetApiKeyValid(namespace, name, true)
+		SetContent(namespace, name, 3, 2, requested, requested)
+
+		SetDeleting(namespace, name, requested)
+
+		Expect(value(appDeletion.WithLabelValues(namespace, name))).To(Equal(float64(requested.Unix())))
+		Expect(collect(apiKeyValid)).To(BeEmpty())
+		Expect(collect(contentNextUpdate)).To(BeEmpty())
+		Expect(collect(contentAge)).To(BeEmpty())
+	})
+
+	It("should report the content and the seconds since the last update", func() {
+		updated := time.Now()
+		contentAge.now = func() time.Time { return updated.Add(90 * time.Second) }
+		SetContent(namespace, name, 3, 2, updated, updated.Add(time.Hour))
+
+		Expect(value(contentModels.WithLabelValues(namespace, name))).To(Equal(3.0))
+		Expect(value(contentImages.WithLabelValues(namespace, name))).To(Equal(2.0))
+		Expect(value(contentNextUpdate.WithLabelValues(namespace, name))).
+			To(Equal(float64(updated.Add(time.Hour).Unix())))
+		series := collect(contentAge)
+		Expect(series).To(HaveLen(1))
+		Expect(series[0].Gauge.GetValue()).To(Equal(90.0))
+	})
+
+	It("should delete the series of a deleted OdhNimApp", func() {
+		ObserveApiKeyValidation(namespace, name, "ApiKeyInvalid", time.Second)
+		ObserveCatalogRefresh(namespace, name, ResultSuccess, time.Second)
+		SetContent(namespace, name, 3, 2, time.Now(), time.Now())
+		ObserveCatalogRefresh(namespace, "other-app", ResultSuccess, time.Second)
+		DeferCleanup(DeleteApp, namespace, "other-app")
+
+		DeleteApp(namespace, name)
+
+		Expect(collect(apiKeyValidations)).To(BeEmpty())
+		Expect(collect(catalogRefreshDuration)).To(HaveLen(1))
+		Expect(collect(contentModels)).To(BeEmpty())
+		Expect(collect(contentImages)).To(BeEmpty())
+		Expect(collect(contentAge)).To(BeEmpty())
+	})
+
+	It("should label the OdhNimApp series with the app namespace and name", func() {
+		SetDeleting(namespace, name, time.Now())
+		ObserveApiKeyValidation(namespace, name, "ApiKeyInvalid", time.Second)
+		SetApiKeyValid(namespace, name, false)
+		ObserveCatalogRefresh(namespace, name, ResultSuccess, time.Second)
+		SetContent(namespace, name, 3, 2, time.Now(), time.Now())
+
+		for _, collector := range []prometheus.Collector{apiKeyValidations, apiKeyValid, apiKeyValidationDuration,
+			catalogRefreshDuration, contentModels, contentImages, contentNextUpdate, appDeletion, contentAge} {
+			series := collect(collector)
+			Expect(series).NotTo(BeEmpty())
+			for _, metric := range series {
+				labels := map[string]string{}
+				for _, pair := range metric.GetLabel() {
+					labels[pair.GetName()] = pair.GetValue()
+				}
+				Expect(labels).To(HaveKeyWithValue("app_namespace", namespace))
+				Expect(labels).To(HaveKeyWithValue("app_name", name))
+				Expect(labels).NotTo(HaveKey("namespace"))
+				Expect(labels).NotTo(HaveKey("name"))
+			}
+		}
+	})
+
+	It("should count the catalog cache lookups by result", func() {
+		hits := value(catalogCacheLookups.WithLabelValues(CacheHit))
+		misses := value(catalogCacheLookups.WithLabelValues(CacheMiss))
+
+		ObserveCatalogCacheLookup(CacheHit)
+		ObserveCatalogCacheLookup(CacheHit)
+		ObserveCatalogCacheLookup(CacheMiss)
+
+		Expect(value(catalogCacheLookups.WithLabelValues(CacheHit))).To(Equal(hits + 2))
+		Expect(value(catalogCacheLookups.WithLabelValues(CacheMiss))).To(Equal(misses + 1))
+	})
+
+	It("should count the NGC requests by status code", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+		}))
+		DeferCleanup(server.Close)
+
+		before := value(ngcRequests.WithLabelValues("get", "401"))
+		httpClient := &http.Client{Transport: InstrumentNgcTransport(http.DefaultTransport)}
+		resp, err := httpClient.Get(server.URL)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.Body.Close()).To(Succeed())
+
+		Expect(value(ngcRequests.WithLabelValues("get", "401"))).To(Equal(before + 1))
+	})
+})
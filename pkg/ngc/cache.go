@@ -0,0 +1,152 @@
+// Copyright (c) 2024 Red Hat, Inc.
+
+package ngc
+
+// This file hosts the shared catalog cache. The NIM catalog is the same for every API key entitled to the NGC org, so
+// catalogs are cached in memory keyed by the NGC API url and org, and shared by OdhNimApps across namespaces and API
+// keys. The API keys are validated by the OdhNimApps before requesting the catalog, so a catalog is fetched once per
+// TTL for the whole org: the first API key requesting an expired catalog exchanges its token and fetches it, and the
+// concurrent requests of every other API key join that fetch.
+//
+// Expired catalogs are revalidated using the ETags of the NGC responses they were built from, unmodified responses are
+// reused.
+
+import (
+	"context"
+	"github.com/opendatahub-io/odh-nim-operator/pkg/metrics"
+	"golang.org/x/sync/singleflight"
+	"slices"
+	"sync"
+	"time"
+)
+
+// catalogCacheIdleTimeout is the time catalogs not requested are evicted after, longer than the default daily
+// schedule, so scheduled refreshes revalidate their catalog
+const catalogCacheIdleTimeout = 48 * time.Hour
+
+// catalogCache is used for caching catalogs keyed by catalogCacheKey, the zero value is ready for use
+type catalogCache struct {
+	mu      sync.Mutex
+	entries map[string]*catalogEntry
+	fetches singleflight.Group
+}
+
+// catalogEntry is used for encapsulating a cached catalog and the NGC responses it was built from keyed by url
+type catalogEntry struct {
+	models    []Model
+	fetched   time.Time
+	requested time.Time
+	responses map[string]cachedResponse
+}
+
+// cachedResponse is used for encapsulating the body of an NGC response and its ETag
+type cachedResponse struct {
+	etag string
+	body []byte
+}
+
+// catalogFetch is used for tracking the NGC responses of a catalog fetch, the previous responses are revalidated
+type catalogFetch struct {
+	previous  map[string]cachedResponse
+	responses map[string]cachedResponse
+}
+
+// RefreshCatalog is used for fetching the NIM models available for an API key like GetCatalog, ignoring the TTL of the
+// cached catalog. Unmodified NGC responses are still reused, and concurrent fetches are still deduplicated.
+func (c *Client) RefreshCatalog(ctx context.Context, apiKey string) ([]Model, error) {
+	return c.getCachedCatalog(ctx, apiKey, true)
+}
+
+// getCachedCatalog is used for serving the catalog of the org from the cache, fetching it with the API key if missing,
+// expired, or refreshed. Concurrent fetches are deduplicated per org, whichever API key they were requested with. A
+// zero or negative TTL disables serving cached catalogs.
+func (c *Client) getCachedCatalog(ctx context.Context, apiKey string, refresh bool) ([]Model, error) {
+	key := c.catalogCacheKey()
+	fresh := func(entry *catalogEntry) bool {
+		return entry != nil && !refresh && c.opts.CatalogTTL > 0 && time.Since(entry.fetched) < c.opts.CatalogTTL
+	}
+
+	if entry := c.catalogs.get(key); fresh(entry) {
+		metrics.ObserveCatalogCacheLookup(metrics.CacheHit)
+		return copyModels(entry.models), nil
+	}
+	metrics.ObserveCatalogCacheLookup(metrics.CacheMiss)
+
+	models, err, _ := c.catalogs.fetches.Do(key, func() (any, error) {
+		// a fetch completed since the lookup
+		entry := c.catalogs.get(key)
+		if fresh(entry) {
+			return entry.models, nil
+		}
+
+		token, err := c.GetToken(ctx, apiKey)
+		if err != nil {
+			return nil, err
+		}
+		fetch := &catalogFetch{responses: map[string]cachedResponse{}}
+		if entry != nil {
+			fetch.previous = entry.responses
+		}
+		resourceIds, err := c.searchCatalog(ctx, token, fetch)
+		if err != nil {
+			return nil, err
+		}
+		models, err := c.fetchRepositories(ctx, resourceIds, token, fetch)
+		if err != nil {
+			return nil, err
+		}
+		c.catalogs.set(key, &catalogEntry{models: models, fetched: time.Now(), responses: fetch.responses})
+		return models, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return copyModels(models.([]Model)), nil
+}
+
+// get is used for getting a cached catalog, nil if missing, the catalog is marked as requested
+func (c *catalogCache) get(key string) *catalogEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, found := c.entries[key]
+	if !found {
+		return nil
+	}
+	entry.requested = time.Now()
+	return entry
+}
+
+// set is used for caching a catalog, catalogs not requested for catalogCacheIdleTimeout are evicted
+func (c *catalogCache) set(key string, entry *catalogEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for cachedKey, cached := range c.entries {
+		if now.Sub(cached.requested) > catalogCacheIdleTimeout {
+			delete(c.entries, cachedKey)
+		}
+	}
+
+	if c.entries == nil {
+		c.entries = map[string]*catalogEntry{}
+	}
+	entry.requested = now
+	c.entries[key] = entry
+}
+
+// catalogCacheKey is used for building the cache key of the catalog, the NGC API url and org
+func (c *Client) catalogCacheKey() string {
+	return c.opts.ApiUrl + "/" + catalogOrg
+}
+
+// copyModels is used for copying cached models, so callers can't modify the cache
+func copyModels(models []Model) []Model {
+	copied := make([]Model, len(models))
+	for i, model := range models {
+		model.Tags = slices.Clone(model.Tags)
+		copied[i] = model
+	}
+	return copied
+}
@@ -0,0 +1,142 @@
+// Copyright (c) 2024 Red Hat, Inc.
+
+package ngc
+
+import (
+	"encoding/json"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var _ = Describe("Catalog cache", func() {
+	var server *httptest.Server
+	var release chan struct{}
+	var searches, repositories, notModified atomic.Int32
+
+	BeforeEach(func() {
+		release = nil
+		searches.Store(0)
+		repositories.Store(0)
+		notModified.Store(0)
+
+		mux := http.NewServeMux()
+		mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+			_, apiKey, _ := r.BasicAuth()
+			if apiKey != "my-api-key" && apiKey != "my-other-api-key" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"token": "token-" + apiKey})
+		})
+		mux.HandleFunc("/v2/search/catalog/resources/CONTAINER", func(w http.ResponseWriter, r *http.Request) {
+			searches.Add(1)
+			if release != nil {
+				<-release
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"resultPageTotal": 1,
+				"results": []any{map[string]any{
+					"resources": []any{map[string]string{"resourceId": "nim/meta/llama3-8b-instruct"}},
+				}},
+			})
+		})
+		mux.HandleFunc("/v2/org/nim/team/meta/repos/llama3-8b-instruct", func(w http.ResponseWriter, r *http.Request) {
+			repositories.Add(1)
+			w.Header().Set("ETag", `"v1"`)
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				notModified.Add(1)
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"name":      "llama3-8b-instruct",
+				"tags":      []string{"1.0.0"},
+				"latestTag": "1.0.0",
+			})
+		})
+		server = httptest.NewServer(mux)
+		DeferCleanup(server.Close)
+	})
+
+	newClient := func(ttl time.Duration) *Client {
+		client, err := NewClient(ClientOptions{AuthUrl: server.URL, ApiUrl: server.URL, CatalogTTL: ttl})
+		Expect(err).NotTo(HaveOccurred())
+		return client
+	}
+
+	expectCatalog := func(models []Model, err error) {
+		Expect(err).NotTo(HaveOccurred())
+		Expect(models).To(HaveLen(1))
+		Expect(models[0].Name).To(Equal("llama3-8b-instruct"))
+		Expect(models[0].Tags).To(Equal([]string{"1.0.0"}))
+	}
+
+	It("should serve the cached catalog until the TTL expires", func(ctx SpecContext) {
+		client := newClient(time.Hour)
+
+		models, err := client.GetCatalog(ctx, "my-api-key")
+		expectCatalog(models, err)
+		models[0].Tags[0] = "modified"
+
+		expectCatalog(client.GetCatalog(ctx, "my-api-key"))
+		Expect(searches.Load()).To(Equal(int32(1)))
+
+		By("sharing the catalog with the other API keys of the org")
+		expectCatalog(client.GetCatalog(ctx, "my-other-api-key"))
+		Expect(searches.Load()).To(Equal(int32(1)))
+		Expect(repositories.Load()).To(Equal(int32(1)))
+
+		By("refreshing the catalog ignoring the TTL")
+		expectCatalog(client.RefreshCatalog(ctx, "my-other-api-key"))
+		Expect(searches.Load()).To(Equal(int32(2)))
+		Expect(repositories.Load()).To(Equal(int32(2)))
+	})
+
+	It("should revalidate expired catalogs using the ETags", func(ctx SpecContext) {
+		client := newClient(-1)
+
+		expectCatalog(client.GetCatalog(ctx, "my-api-key"))
+		expectCatalog(client.GetCatalog(ctx, "my-api-key"))
+		Expect(searches.Load()).To(Equal(int32(2)))
+		Expect(repositories.Load()).To(Equal(int32(2)))
+		Expect(notModified.Load()).To(Equal(int32(1)))
+	})
+
+	It("should not cache failed fetches", func(ctx SpecContext) {
+		client := newClient(time.Hour)
+
+		_, err := client.GetCatalog(ctx, "my-invalid-api-key")
+		Expect(err).To(MatchError(ErrInvalidApiKey))
+		_, err = client.GetCatalog(ctx, "my-invalid-api-key")
+		Expect(err).To(MatchError(ErrInvalidApiKey))
+		Expect(client.catalogs.entries).To(BeEmpty())
+	})
+
+	It("should deduplicate concurrent fetches across API keys", func(ctx SpecContext) {
+		client := newClient(time.Hour)
+		release = make(chan struct{})
+
+		var wg sync.WaitGroup
+		for _, apiKey := range []string{"my-api-key", "my-other-api-key", "my-api-key", "my-other-api-key"} {
+			wg.Add(1)
+			go func(apiKey string) {
+				defer GinkgoRecover()
+				defer wg.Done()
+				expectCatalog(client.GetCatalog(ctx, apiKey))
+			}(apiKey)
+		}
+		Eventually(searches.Load).Should(Equal(int32(1)))
+		// let the waiting callers join the fetch in flight
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+		wg.Wait()
+
+		Expect(searches.Load()).To(Equal(int32(1)))
+		Expect(repositories.Load()).To(Equal(int32(1)))
+	})
+})
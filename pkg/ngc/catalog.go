@@ -0,0 +1,220 @@
+// Copyright (c) 2024 Red Hat, Inc.
+
+package ngc
+
+// This file hosts the NIM catalog fetcher. The catalog is a list of Model documents, the content ConfigMap holds each
+// Model encoded as JSON keyed by the Model name, for instance:
+//
+//	llama3-8b-instruct: |
+//	  {
+//	    "name": "llama3-8b-instruct",
+//	    "displayName": "Llama3-8b-instruct",
+//	    "shortDescription": "Llama3-8B-Instruct is a large language model",
+//	    "namespace": "nim/meta",
+//	    "image": "nvcr.io/nim/meta/llama3-8b-instruct",
+//	    "tags": ["1.0.0", "latest"],
+//	    "latestTag": "1.0.0",
+//	    "updatedDate": "2024-06-01T00:00:00.000Z",
+//	    "digest": "sha256:4d2b2d1f2ee6a1d0ec4fc5e5c7b3f8e6b47a6ab7d0d3c8c5d6e2f1a0b9c8d7e6"
+//	  }
+//
+// The digest is the digest of the image of the latest tag, it is missing from catalogs not reporting it.
+//
+// Images rewritten to a registry mirror keep the original image in the sourceImage field.
+//
+// The Model document schema is versioned by CatalogSchemaVersion, fields can be added without bumping the version,
+// removing or changing existing fields requires a bump.
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	DefaultRegistry = "nvcr.io"
+
+	// CatalogSchemaVersion is the version of the Model document schema
+	CatalogSchemaVersion = "v1"
+
+	catalogPageSize = 100
+	catalogOrg      = "nim"
+	catalogLabel    = "nvidia_nim"
+)
+
+// Model is used for encapsulating a NIM model available in the catalog
+type Model struct {
+	Name             string   `json:"name"`
+	DisplayName      string   `json:"displayName"`
+	ShortDescription string   `json:"shortDescription"`
+	Namespace        string   `json:"namespace"`
+	Image            string   `json:"image"`
+	Tags             []string `json:"tags"`
+	LatestTag        string   `json:"latestTag"`
+	UpdatedDate      string   `json:"updatedDate"`
+	Digest           string   `json:"digest,omitempty"`
+	SourceImage      string   `json:"sourceImage,omitempty"`
+}
+
+// searchQuery is used for encoding the NGC catalog search query
+type searchQuery struct {
+	Query    string         `json:"query"`
+	Page     int            `json:"page"`
+	PageSize int            `json:"pageSize"`
+	Filters  []searchFilter `json:"filters"`
+}
+
+type searchFilter struct {
+	Field string `json:"field"`
+	Value string `json:"value"`
+}
+
+// searchResponse is used for decoding the NGC catalog search response
+type searchResponse struct {
+	ResultPageTotal int `json:"resultPageTotal"`
+	Results         []struct {
+		Resources []struct {
+			ResourceId string `json:"resourceId"`
+		} `json:"resources"`
+	} `json:"results"`
+}
+
+// repositoryResponse is used for decoding the NGC repository response
+type repositoryResponse struct {
+	Name             string   `json:"name"`
+	DisplayName      string   `json:"displayName"`
+	ShortDescription string   `json:"shortDescription"`
+	Namespace        string   `json:"namespace"`
+	Tags             []string `json:"tags"`
+	LatestTag        string   `json:"latestTag"`
+	UpdatedDate      string   `json:"updatedDate"`
+	LatestImageSha   string   `json:"latestImageSha256"`
+}
+
+// GetCatalog is used for getting the NIM models available for an API key, served from the catalog cache shared by the
+// API keys of the org until its TTL expires (see cache.go). Returns ErrInvalidApiKey if NGC rejected the key,
+// ErrRateLimited if a request was held off, or ErrUnreachable if NGC could not be reached or responded unexpectedly.
+func (c *Client) GetCatalog(ctx context.Context, apiKey string) ([]Model, error) {
+	return c.getCachedCatalog(ctx, apiKey, false)
+}
+
+// searchCatalog is used for searching the catalog page by page for NIM repositories, returns their resource ids
+func (c *Client) searchCatalog(ctx context.Context, token string, fetch *catalogFetch) ([]string, error) {
+	var resourceIds []string
+	for page, pages := 0, 1; page < pages; page++ {
+		search := &searchResponse{}
+		if err := c.getJson(ctx, c.searchUrl(page), token, fetch, search); err != nil {
+			return nil, err
+		}
+		for _, result := range search.Results {
+			for _, resource := range result.Resources {
+				resourceIds = append(resourceIds, resource.ResourceId)
+			}
+		}
+		pages = search.ResultPageTotal
+	}
+	return resourceIds, nil
+}
+
+// fetchRepositories is used for fetching the details of the NIM repositories found by searchCatalog
+func (c *Client) fetchRepositories(ctx context.Context, resourceIds []string, token string,
+	fetch *catalogFetch) ([]Model, error) {
+	models := make([]Model, 0, len(resourceIds))
+	for _, resourceId := range resourceIds {
+		repo := &repositoryResponse{}
+		if err := c.getJson(ctx, c.repositoryUrl(resourceId), token, fetch, repo); err != nil {
+			return nil, err
+		}
+		models = append(models, Model{
+			Name:             repo.Name,
+			DisplayName:      repo.DisplayName,
+			ShortDescription: repo.ShortDescription,
+			Namespace:        repo.Namespace,
+			Image:            fmt.Sprintf("%s/%s", DefaultRegistry, resourceId),
+			Tags:             repo.Tags,
+			LatestTag:        repo.LatestTag,
+			UpdatedDate:      repo.UpdatedDate,
+			Digest:           imageDigest(repo.LatestImageSha),
+		})
+	}
+
+	return models, nil
+}
+
+// imageDigest is used for prefixing the sha256 image digest reported by NGC with its algorithm, empty if not reported
+func imageDigest(sha string) string {
+	if sha == "" || strings.HasPrefix(sha, "sha256:") {
+		return sha
+	}
+	return "sha256:" + sha
+}
+
+// searchUrl is used for building the NGC catalog search url for a specific page
+func (c *Client) searchUrl(page int) string {
+	query, _ := json.Marshal(searchQuery{
+		Query:    fmt.Sprintf("orgName:%s", catalogOrg),
+		Page:     page,
+		PageSize: catalogPageSize,
+		Filters:  []searchFilter{{Field: "labels", Value: catalogLabel}},
+	})
+	return fmt.Sprintf("%s/v2/search/catalog/resources/CONTAINER?q=%s", c.opts.ApiUrl, url.QueryEscape(string(query)))
+}
+
+// repositoryUrl is used for building the NGC repository url from a resource id, i.e. org/team/name or org/name
+func (c *Client) repositoryUrl(resourceId string) string {
+	parts := strings.Split(resourceId, "/")
+	name := parts[len(parts)-1]
+	path := fmt.Sprintf("org/%s/repos/%s", parts[0], name)
+	if len(parts) > 2 {
+		path = fmt.Sprintf("org/%s/team/%s/repos/%s", parts[0], parts[1], name)
+	}
+	return fmt.Sprintf("%s/v2/%s?resolve-labels=true", c.opts.ApiUrl, path)
+}
+
+// getJson is used for sending an authorized GET request to NGC and decoding the JSON response into target, previous
+// responses of the fetch are revalidated using their ETag, and responses with an ETag are tracked by the fetch
+func (c *Client) getJson(ctx context.Context, endpoint, token string, fetch *catalogFetch, target any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/json")
+	previous, revalidate := fetch.previous[endpoint]
+	if revalidate {
+		req.Header.Set("If-None-Match", previous.etag)
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var body []byte
+	switch {
+	case resp.StatusCode == http.StatusNotModified && revalidate:
+		body = previous.body
+		fetch.responses[endpoint] = previous
+	case resp.StatusCode == http.StatusOK:
+		if body, err = io.ReadAll(resp.Body); err != nil {
+			return fmt.Errorf("%w: failed reading response from %s", ErrUnreachable, req.URL.Path)
+		}
+		if etag := resp.Header.Get("ETag"); etag != "" {
+			fetch.responses[endpoint] = cachedResponse{etag: etag, body: body}
+		}
+	case resp.StatusCode == http.StatusUnauthorized, resp.StatusCode == http.StatusForbidden:
+		return ErrInvalidApiKey
+	default:
+		return fmt.Errorf("%w: unexpected status %s from %s", ErrUnreachable, resp.Status, req.URL.Path)
+	}
+
+	if err = json.Unmarshal(body, target); err != nil {
+		return fmt.Errorf("%w: malformed response from %s", ErrUnreachable, req.URL.Path)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+// Copyright (c) 2024 Red Hat, Inc.
+
+package ngc
+
+import (
+	"encoding/json"
+	"fmt"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"net/http"
+	"net/http/httptest"
+)
+
+var _ = Describe("Catalog", func() {
+	var server *httptest.Server
+	var client *Client
+	var searchPages [][]string
+
+	BeforeEach(func() {
+		searchPages = [][]string{
+			{"nim/meta/llama3-8b-instruct", "nim/mistralai/mistral-7b-instruct"},
+			{"nim/nvidia-embed"},
+		}
+
+		mux := http.NewServeMux()
+		mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+			if _, apiKey, _ := r.BasicAuth(); apiKey != "my-api-key" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"token": "my-token"})
+		})
+		mux.HandleFunc("/v2/search/catalog/resources/CONTAINER", func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.Header.Get("Authorization")).To(Equal("Bearer my-token"))
+			query := &searchQuery{}
+			Expect(json.Unmarshal([]byte(r.URL.Query().Get("q")), query)).To(Succeed())
+
+			var resources []map[string]string
+			for _, resourceId := range searchPages[query.Page] {
+				resources = append(resources, map[string]string{"resourceId": resourceId})
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"resultPageTotal": len(searchPages),
+				"results":         []any{map[string]any{"resources": resources}},
+			})
+		})
+		mux.HandleFunc("/v2/org/", func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.Header.Get("Authorization")).To(Equal("Bearer my-token"))
+			repos := map[string]string{
+				"/v2/org/nim/team/meta/repos/llama3-8b-instruct":       "llama3-8b-instruct",
+				"/v2/org/nim/team/mistralai/repos/mistral-7b-instruct": "mistral-7b-instruct",
+				"/v2/org/nim/repos/nvidia-embed":                       "nvidia-embed",
+			}
+			name, found := repos[r.URL.Path]
+			if !found {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"name":              name,
+				"displayName":       fmt.Sprintf("%s display", name),
+				"tags":              []string{"1.0.0", "latest"},
+				"latestTag":         "1.0.0",
+				"latestImageSha256": "4d2b2d1f2ee6a1d0",
+			})
+		})
+		server = httptest.NewServer(mux)
+		DeferCleanup(server.Close)
+
+		var err error
+		client, err = NewClient(ClientOptions{AuthUrl: server.URL, ApiUrl: server.URL})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should fetch the models from all the search pages", func(ctx SpecContext) {
+		models, err := client.GetCatalog(ctx, "my-api-key")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(models).To(HaveLen(3))
+		Expect(models[0]).To(Equal(Model{
+			Name:        "llama3-8b-instruct",
+			DisplayName: "llama3-8b-instruct display",
+			Image:       "nvcr.io/nim/meta/llama3-8b-instruct",
+			Tags:        []string{"1.0.0", "latest"},
+			LatestTag:   "1.0.0",
+			Digest:      "sha256:4d2b2d1f2ee6a1d0",
+		}))
+		Expect(models[2].Image).To(Equal("nvcr.io/nim/nvidia-embed"))
+	})
+
+	It("should report an invalid API key", func(ctx SpecContext) {
+		_, err := client.GetCatalog(ctx, "my-other-api-key")
+		Expect(err).To(MatchError(ErrInvalidApiKey))
+	})
+
+	It("should report NGC as unreachable when a repository fails", func(ctx SpecContext) {
+		searchPages[1] = append(searchPages[1], "nim/unknown")
+		_, err := client.GetCatalog(ctx, "my-api-key")
+		Expect(err).To(MatchError(ErrUnreachable))
+	})
+})
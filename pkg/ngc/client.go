@@ -0,0 +1,240 @@
+// Copyright (c) 2024 Red Hat, Inc.
+
+package ngc
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/opendatahub-io/odh-nim-operator/pkg/metrics"
+	"golang.org/x/net/http/httpproxy"
+	"golang.org/x/time/rate"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultAuthUrl = "https://authn.nvidia.com"
+	DefaultApiUrl  = "https://api.ngc.nvidia.com"
+	DefaultTimeout = 30 * time.Second
+)
+
+var (
+	// ErrInvalidApiKey is returned when NGC rejects the API key
+	ErrInvalidApiKey = errors.New("invalid NGC API key")
+	// ErrUnreachable is returned when NGC can not be reached or responds unexpectedly
+	ErrUnreachable = errors.New("NGC unreachable")
+	// ErrRateLimited is returned when requests are held off by NGC or by the client-side rate limiter
+	ErrRateLimited = errors.New("NGC rate limited")
+)
+
+// ClientOptions is used for encapsulating the NGC client options
+type ClientOptions struct {
+	AuthUrl            string
+	ApiUrl             string
+	Timeout            time.Duration
+	CaFile             string
+	InsecureSkipVerify bool
+	// RateLimit is the requests per second allowed by the client-side rate limiter, bursting up to RateBurst requests,
+	// a negative rate limit disables the limiter
+	RateLimit float64
+	RateBurst int
+	// CatalogTTL is the time cached catalogs are served for, a zero or negative TTL disables serving cached catalogs
+	CatalogTTL time.Duration
+}
+
+// TransportOptions is used for encapsulating the NGC transport options reloaded at runtime, the proxy environment
+// variables are used if no proxy is set, the CA bundle is trusted in addition to the system pool and the CA file
+type TransportOptions struct {
+	HttpProxy  string
+	HttpsProxy string
+	NoProxy    string
+	CaBundle   []byte
+}
+
+// Client is used for communicating with NVIDIA GPU Cloud (NGC)
+type Client struct {
+	opts     ClientOptions
+	limiter  *rate.Limiter
+	catalogs catalogCache
+
+	mu            sync.RWMutex
+	httpClient    *http.Client
+	transportOpts TransportOptions
+	heldOffUntil  time.Time
+}
+
+// tokenResponse is used for decoding the NGC token exchange response
+type tokenResponse struct {
+	Token     string `json:"token"`
+	ExpiresIn int    `json:"expires_in"`
+}
+
+// NewClient is a factory function for creating an NGC client, empty options are set to their defaults
+func NewClient(opts ClientOptions) (*Client, error) {
+	if opts.AuthUrl == "" {
+		opts.AuthUrl = DefaultAuthUrl
+	}
+	if opts.ApiUrl == "" {
+		opts.ApiUrl = DefaultApiUrl
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.RateLimit == 0 {
+		opts.RateLimit = DefaultRateLimit
+	}
+	if opts.RateBurst <= 0 {
+		opts.RateBurst = DefaultRateBurst
+	}
+	opts.AuthUrl = strings.TrimSuffix(opts.AuthUrl, "/")
+	opts.ApiUrl = strings.TrimSuffix(opts.ApiUrl, "/")
+
+	transport, err := newTransport(opts, TransportOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var limiter *rate.Limiter
+	if opts.RateLimit > 0 {
+		limiter = rate.NewLimiter(rate.Limit(opts.RateLimit), opts.RateBurst)
+	}
+
+	return &Client{
+		opts:       opts,
+		limiter:    limiter,
+		httpClient: &http.Client{Timeout: opts.Timeout, Transport: transport},
+	}, nil
+}
+
+// Reload is used for replacing the transport with one built from the transport options, returns false if the options
+// didn't change. Requests in flight complete using the previous transport, its idle connections are closed.
+func (c *Client) Reload(transportOpts TransportOptions) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if reflect.DeepEqual(c.transportOpts, transportOpts) {
+		return false, nil
+	}
+
+	transport, err := newTransport(c.opts, transportOpts)
+	if err != nil {
+		return false, err
+	}
+
+	previous := c.httpClient
+	c.httpClient = &http.Client{Timeout: c.opts.Timeout, Transport: transport}
+	c.transportOpts = transportOpts
+	previous.CloseIdleConnections()
+	return true, nil
+}
+
+// getHttpClient is used for getting the http client using the current transport
+func (c *Client) getHttpClient() *http.Client {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.httpClient
+}
+
+// do is used for sending a request to NGC through the rate limiter, throttling responses are returned as ErrRateLimited
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	if err := c.throttle(req.Context()); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.getHttpClient().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnreachable, err.Error())
+	}
+	if err = c.checkRateLimited(resp); err != nil {
+		_ = resp.Body.Close()
+		return nil, err
+	}
+	return resp, nil
+}
+
+// newTransport is used for building the instrumented NGC transport, the CA file and bundle are trusted in addition to
+// the system pool
+func newTransport(opts ClientOptions, transportOpts TransportOptions) (http.RoundTripper, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: opts.InsecureSkipVerify}
+	if opts.CaFile != "" || len(transportOpts.CaBundle) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if opts.CaFile != "" {
+			caBundle, err := os.ReadFile(opts.CaFile)
+			if err != nil {
+				return nil, err
+			}
+			if !pool.AppendCertsFromPEM(caBundle) {
+				return nil, fmt.Errorf("no certificates found in %s", opts.CaFile)
+			}
+		}
+		if len(transportOpts.CaBundle) > 0 && !pool.AppendCertsFromPEM(transportOpts.CaBundle) {
+			return nil, errors.New("no certificates found in the trusted CA bundle")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	if transportOpts.HttpProxy != "" || transportOpts.HttpsProxy != "" {
+		proxyFunc := (&httpproxy.Config{
+			HTTPProxy:  transportOpts.HttpProxy,
+			HTTPSProxy: transportOpts.HttpsProxy,
+			NoProxy:    transportOpts.NoProxy,
+		}).ProxyFunc()
+		transport.Proxy = func(req *http.Request) (*url.URL, error) {
+			return proxyFunc(req.URL)
+		}
+	}
+
+	return metrics.InstrumentNgcTransport(transport), nil
+}
+
+// GetToken is used for exchanging an API key for an NGC access token. Returns ErrInvalidApiKey if NGC rejected the key,
+// ErrRateLimited if the request was held off, or ErrUnreachable if NGC could not be reached or responded unexpectedly.
+func (c *Client) GetToken(ctx context.Context, apiKey string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.opts.AuthUrl+"/token?service=ngc", nil)
+	if err != nil {
+		return "", err
+	}
+	req.SetBasicAuth("$oauthtoken", apiKey)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		token := &tokenResponse{}
+		if err = json.NewDecoder(resp.Body).Decode(token); err != nil || token.Token == "" {
+			return "", fmt.Errorf("%w: malformed token response", ErrUnreachable)
+		}
+		return token.Token, nil
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return "", ErrInvalidApiKey
+	default:
+		return "", fmt.Errorf("%w: unexpected status %s", ErrUnreachable, resp.Status)
+	}
+}
+
+// ValidateApiKey is used for validating an API key against NGC, returns nil if the key is valid. Returns
+// ErrInvalidApiKey if NGC rejected the key, ErrRateLimited if the request was held off, or ErrUnreachable if NGC could
+// not be reached or responded unexpectedly.
+func (c *Client) ValidateApiKey(ctx context.Context, apiKey string) error {
+	_, err := c.GetToken(ctx, apiKey)
+	return err
+}
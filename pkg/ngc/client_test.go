@@ -0,0 +1,136 @@
+// Copyright (c) 2024 Red Hat, Inc.
+
+package ngc
+
+import (
+	"encoding/json"
+	"encoding/pem"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+var _ = Describe("Client", func() {
+	var server *httptest.Server
+	var status int
+	var body any
+
+	BeforeEach(func() {
+		status = http.StatusOK
+		body = map[string]any{"token": "my-token", "expires_in": 300}
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if user, apiKey, ok := r.BasicAuth(); !ok || user != "$oauthtoken" || apiKey != "my-api-key" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.WriteHeader(status)
+			_ = json.NewEncoder(w).Encode(body)
+		}))
+		DeferCleanup(server.Close)
+	})
+
+	newClient := func(opts ClientOptions) *Client {
+		client, err := NewClient(opts)
+		Expect(err).NotTo(HaveOccurred())
+		return client
+	}
+
+	It("should exchange a valid API key for a token", func(ctx SpecContext) {
+		token, err := newClient(ClientOptions{AuthUrl: server.URL}).GetToken(ctx, "my-api-key")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(token).To(Equal("my-token"))
+	})
+
+	It("should report an invalid API key", func(ctx SpecContext) {
+		err := newClient(ClientOptions{AuthUrl: server.URL}).ValidateApiKey(ctx, "my-other-api-key")
+		Expect(err).To(MatchError(ErrInvalidApiKey))
+	})
+
+	It("should report NGC as unreachable for unexpected responses", func(ctx SpecContext) {
+		status = http.StatusServiceUnavailable
+		err := newClient(ClientOptions{AuthUrl: server.URL}).ValidateApiKey(ctx, "my-api-key")
+		Expect(err).To(MatchError(ErrUnreachable))
+	})
+
+	It("should report NGC as unreachable for malformed tokens", func(ctx SpecContext) {
+		body = map[string]any{"expires_in": 300}
+		err := newClient(ClientOptions{AuthUrl: server.URL}).ValidateApiKey(ctx, "my-api-key")
+		Expect(err).To(MatchError(ErrUnreachable))
+	})
+
+	It("should report NGC as unreachable when timing out", func(ctx SpecContext) {
+		slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(100 * time.Millisecond)
+		}))
+		DeferCleanup(slow.Close)
+
+		err := newClient(ClientOptions{AuthUrl: slow.URL, Timeout: 10 * time.Millisecond}).ValidateApiKey(ctx, "my-api-key")
+		Expect(err).To(MatchError(ErrUnreachable))
+	})
+
+	It("should verify TLS using the custom CA bundle", func(ctx SpecContext) {
+		tlsServer := httptest.NewTLSServer(server.Config.Handler)
+		DeferCleanup(tlsServer.Close)
+
+		caFile := filepath.Join(GinkgoT().TempDir(), "ca.crt")
+		caBundle := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: tlsServer.Certificate().Raw})
+		Expect(os.WriteFile(caFile, caBundle, 0600)).To(Succeed())
+
+		Expect(newClient(ClientOptions{AuthUrl: tlsServer.URL}).ValidateApiKey(ctx, "my-api-key")).
+			To(MatchError(ErrUnreachable))
+		Expect(newClient(ClientOptions{AuthUrl: tlsServer.URL, CaFile: caFile}).ValidateApiKey(ctx, "my-api-key")).
+			To(Succeed())
+		Expect(newClient(ClientOptions{AuthUrl: tlsServer.URL, InsecureSkipVerify: true}).ValidateApiKey(ctx, "my-api-key")).
+			To(Succeed())
+	})
+
+	It("should reload the transport trusting the CA bundle", func(ctx SpecContext) {
+		tlsServer := httptest.NewTLSServer(server.Config.Handler)
+		DeferCleanup(tlsServer.Close)
+		caBundle := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: tlsServer.Certificate().Raw})
+
+		client := newClient(ClientOptions{AuthUrl: tlsServer.URL})
+		Expect(client.ValidateApiKey(ctx, "my-api-key")).To(MatchError(ErrUnreachable))
+
+		reloaded, err := client.Reload(TransportOptions{CaBundle: caBundle})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reloaded).To(BeTrue())
+		Expect(client.ValidateApiKey(ctx, "my-api-key")).To(Succeed())
+
+		reloaded, err = client.Reload(TransportOptions{CaBundle: caBundle})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reloaded).To(BeFalse())
+
+		_, err = client.Reload(TransportOptions{CaBundle: []byte("not a certificate")})
+		Expect(err).To(HaveOccurred())
+		Expect(client.ValidateApiKey(ctx, "my-api-key")).To(Succeed())
+	})
+
+	It("should reload the transport sending requests through the proxy", func(ctx SpecContext) {
+		var proxied []string
+		proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			proxied = append(proxied, r.URL.String())
+			server.Config.Handler.ServeHTTP(w, r)
+		}))
+		DeferCleanup(proxy.Close)
+
+		client := newClient(ClientOptions{AuthUrl: "http://authn.ngc.internal"})
+		reloaded, err := client.Reload(TransportOptions{HttpProxy: proxy.URL, NoProxy: "localhost"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reloaded).To(BeTrue())
+
+		Expect(client.ValidateApiKey(ctx, "my-api-key")).To(Succeed())
+		Expect(proxied).To(ConsistOf("http://authn.ngc.internal/token?service=ngc"))
+
+		By("bypassing the proxy for excluded hosts")
+		_, err = client.Reload(TransportOptions{HttpProxy: proxy.URL, NoProxy: ".ngc.internal"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(client.ValidateApiKey(ctx, "my-api-key")).To(MatchError(ErrUnreachable))
+		Expect(proxied).To(HaveLen(1))
+	})
+})
@@ -0,0 +1,190 @@
+// Copyright (c) 2024 Red Hat, Inc.
+
+package ngc
+
+// This file hosts the offline catalog loaders, used in disconnected clusters with no route to NGC. Offline catalogs
+// hold Model documents in the schema produced by GetCatalog (see catalog.go), either as ConfigMap data in the content
+// ConfigMap layout, or in a file mounted in the operator pod:
+//
+//   - a JSON list of Model documents
+//   - a tarball (.tar, .tar.gz or .tgz) of Model documents, one per file
+//   - a directory of Model documents, one per file, i.e. a mounted ConfigMap
+//
+// Hidden files, i.e. the ..data entries of mounted ConfigMaps, are skipped.
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// maxModelSize is the size limit of a single Model document in an offline catalog
+const maxModelSize = 1 << 20
+
+// DecodeCatalog is used for decoding an offline catalog held as ConfigMap data, every model is encoded as JSON keyed by
+// its name, models are returned sorted by name
+func DecodeCatalog(data map[string]string) ([]Model, error) {
+	models := make([]Model, 0, len(data))
+	for key, encoded := range data {
+		model, err := decodeModel([]byte(encoded))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		models = append(models, model)
+	}
+	sort.Slice(models, func(i, j int) bool { return models[i].Name < models[j].Name })
+	return models, nil
+}
+
+// LoadCatalog is used for loading an offline catalog from a file, a tarball, or a directory, models are returned sorted
+// by name
+func LoadCatalog(path string) ([]Model, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var models []Model
+	switch {
+	case info.IsDir():
+		models, err = loadCatalogDir(path)
+	case strings.HasSuffix(path, ".tar"), strings.HasSuffix(path, ".tar.gz"), strings.HasSuffix(path, ".tgz"):
+		models, err = loadCatalogTarball(path)
+	default:
+		models, err = loadCatalogFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(models, func(i, j int) bool { return models[i].Name < models[j].Name })
+	return models, nil
+}
+
+// loadCatalogFile is used for loading a JSON list of Model documents
+func loadCatalogFile(path string) ([]Model, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var documents []json.RawMessage
+	if err = json.Unmarshal(raw, &documents); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	models := make([]Model, 0, len(documents))
+	for i, document := range documents {
+		model, err := decodeModel(document)
+		if err != nil {
+			return nil, fmt.Errorf("%s[%d]: %w", path, i, err)
+		}
+		models = append(models, model)
+	}
+	return models, nil
+}
+
+// loadCatalogDir is used for loading a directory of Model documents, one per file
+func loadCatalogDir(path string) ([]Model, error) {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var models []Model
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		// mounted ConfigMap keys are symlinks, stat follows them
+		filePath := filepath.Join(path, entry.Name())
+		info, err := os.Stat(filePath)
+		if err != nil {
+			return nil, err
+		}
+		if !info.Mode().IsRegular() {
+			continue
+		}
+		file, err := os.Open(filePath)
+		if err != nil {
+			return nil, err
+		}
+		model, err := readModel(file)
+		_ = file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filePath, err)
+		}
+		models = append(models, model)
+	}
+	return models, nil
+}
+
+// loadCatalogTarball is used for loading a tarball of Model documents, one per file, gzip compressed if suffixed .gz
+// or .tgz
+func loadCatalogTarball(path string) ([]Model, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var reader io.Reader = file
+	if !strings.HasSuffix(path, ".tar") {
+		gzipReader, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		defer gzipReader.Close()
+		reader = gzipReader
+	}
+
+	var models []Model
+	tarReader := tar.NewReader(reader)
+	for {
+		header, err := tarReader.Next()
+		if errors.Is(err, io.EOF) {
+			return models, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if header.Typeflag != tar.TypeReg || strings.HasPrefix(filepath.Base(header.Name), ".") {
+			continue
+		}
+		model, err := readModel(tarReader)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", path, header.Name, err)
+		}
+		models = append(models, model)
+	}
+}
+
+// readModel is used for reading a Model document, up to maxModelSize
+func readModel(reader io.Reader) (Model, error) {
+	raw, err := io.ReadAll(io.LimitReader(reader, maxModelSize+1))
+	if err != nil {
+		return Model{}, err
+	}
+	if len(raw) > maxModelSize {
+		return Model{}, fmt.Errorf("model document exceeds %d bytes", maxModelSize)
+	}
+	return decodeModel(raw)
+}
+
+// decodeModel is used for decoding a Model document, the name and the image are required
+func decodeModel(raw []byte) (Model, error) {
+	model := Model{}
+	if err := json.Unmarshal(raw, &model); err != nil {
+		return model, err
+	}
+	if model.Name == "" || model.Image == "" {
+		return model, errors.New("model document requires a name and an image")
+	}
+	return model, nil
+}
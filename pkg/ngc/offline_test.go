@@ -0,0 +1,111 @@
+// Copyright (c) 2024 Red Hat, Inc.
+
+package ngc
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"os"
+	"path/filepath"
+)
+
+var _ = Describe("Offline catalog", func() {
+	llama := `{"name":"llama3-8b-instruct","image":"nvcr.io/nim/meta/llama3-8b-instruct","latestTag":"1.0.0"}`
+	mistral := `{"name":"mistral-7b-instruct","image":"nvcr.io/nim/mistralai/mistral-7b-instruct"}`
+
+	var dir string
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+	})
+
+	writeTarball := func(name string, compress bool, files map[string]string) string {
+		buffer := &bytes.Buffer{}
+		tarWriter := tar.NewWriter(buffer)
+		for fileName, content := range files {
+			Expect(tarWriter.WriteHeader(&tar.Header{
+				Name: fileName, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg,
+			})).To(Succeed())
+			_, err := tarWriter.Write([]byte(content))
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(tarWriter.Close()).To(Succeed())
+
+		raw := buffer.Bytes()
+		if compress {
+			compressed := &bytes.Buffer{}
+			gzipWriter := gzip.NewWriter(compressed)
+			_, err := gzipWriter.Write(raw)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(gzipWriter.Close()).To(Succeed())
+			raw = compressed.Bytes()
+		}
+
+		path := filepath.Join(dir, name)
+		Expect(os.WriteFile(path, raw, 0644)).To(Succeed())
+		return path
+	}
+
+	expectModels := func(models []Model) {
+		Expect(models).To(HaveLen(2))
+		Expect(models[0].Name).To(Equal("llama3-8b-instruct"))
+		Expect(models[0].LatestTag).To(Equal("1.0.0"))
+		Expect(models[1].Name).To(Equal("mistral-7b-instruct"))
+	}
+
+	It("should decode ConfigMap data", func() {
+		models, err := DecodeCatalog(map[string]string{"mistral-7b-instruct": mistral, "llama3-8b-instruct": llama})
+		Expect(err).NotTo(HaveOccurred())
+		expectModels(models)
+	})
+
+	It("should load a JSON list", func() {
+		path := filepath.Join(dir, "catalog.json")
+		Expect(os.WriteFile(path, []byte("["+mistral+","+llama+"]"), 0644)).To(Succeed())
+
+		models, err := LoadCatalog(path)
+		Expect(err).NotTo(HaveOccurred())
+		expectModels(models)
+	})
+
+	It("should load a directory skipping hidden files", func() {
+		Expect(os.WriteFile(filepath.Join(dir, "llama3-8b-instruct"), []byte(llama), 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "mistral-7b-instruct"), []byte(mistral), 0644)).To(Succeed())
+		Expect(os.Mkdir(filepath.Join(dir, "..data"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, ".hidden"), []byte("not a model"), 0644)).To(Succeed())
+
+		models, err := LoadCatalog(dir)
+		Expect(err).NotTo(HaveOccurred())
+		expectModels(models)
+	})
+
+	DescribeTable("loading a tarball",
+		func(name string, compress bool) {
+			path := writeTarball(name, compress, map[string]string{
+				"catalog/llama3-8b-instruct.json":  llama,
+				"catalog/mistral-7b-instruct.json": mistral,
+				"catalog/._llama3-8b-instruct":     "not a model",
+			})
+
+			models, err := LoadCatalog(path)
+			Expect(err).NotTo(HaveOccurred())
+			expectModels(models)
+		},
+		Entry("uncompressed", "catalog.tar", false),
+		Entry("gzip compressed", "catalog.tar.gz", true),
+		Entry("gzip compressed with the short suffix", "catalog.tgz", true),
+	)
+
+	It("should fail for models with no image", func() {
+		_, err := DecodeCatalog(map[string]string{"llama3-8b-instruct": `{"name":"llama3-8b-instruct"}`})
+		Expect(err).To(MatchError(ContainSubstring("llama3-8b-instruct: model document requires")))
+	})
+
+	It("should fail for missing files", func() {
+		_, err := LoadCatalog(filepath.Join(dir, "missing.json"))
+		Expect(os.IsNotExist(err)).To(BeTrue())
+	})
+})
@@ -0,0 +1,132 @@
+// Copyright (c) 2024 Red Hat, Inc.
+
+package ngc
+
+// This file hosts the NGC rate limiting. Requests are throttled by a client-side token bucket shared by all the
+// requests sent using the client, i.e. across OdhNimApp reconciliations. NGC throttling responses, 429 responses and
+// 503 responses with a Retry-After header, hold off all the requests until the Retry-After delay passes. Requests held
+// off fail fast with ErrRateLimited instead of blocking, callers are expected to retry after RetryAfter.
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	DefaultRateLimit  = 5.0
+	DefaultRateBurst  = 10
+	DefaultRetryAfter = time.Minute
+
+	// maxRetryAfter caps the Retry-After delay requested by NGC
+	maxRetryAfter = time.Hour
+	// maxRateLimitWait is the longest wait for the client-side rate limiter before failing fast
+	maxRateLimitWait = 5 * time.Second
+)
+
+// RetryAfter is used for getting the delay until requests are no longer held off, zero if requests are not held off
+func (c *Client) RetryAfter() time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if delay := time.Until(c.heldOffUntil); delay > 0 {
+		return delay
+	}
+	return 0
+}
+
+// holdOff is used for holding off requests for a delay, an ongoing longer hold off is kept
+func (c *Client) holdOff(delay time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if until := time.Now().Add(delay); until.After(c.heldOffUntil) {
+		c.heldOffUntil = until
+	}
+}
+
+// throttle is used for waiting for the client-side rate limiter before sending a request. Returns ErrRateLimited while
+// requests are held off, or if the wait would exceed maxRateLimitWait, requests are then held off for the wait.
+func (c *Client) throttle(ctx context.Context) error {
+	if retryAfter := c.RetryAfter(); retryAfter > 0 {
+		return fmt.Errorf("%w: retry after %s", ErrRateLimited, retryAfter.Round(time.Second))
+	}
+	if c.limiter == nil {
+		return nil
+	}
+
+	reservation := c.limiter.Reserve()
+	delay := reservation.Delay()
+	if delay > maxRateLimitWait {
+		reservation.Cancel()
+		c.holdOff(delay)
+		return fmt.Errorf("%w: client-side rate limit exceeded, retry after %s", ErrRateLimited,
+			delay.Round(time.Second))
+	}
+	if delay == 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		reservation.Cancel()
+		return fmt.Errorf("%w: %s", ErrUnreachable, ctx.Err().Error())
+	}
+}
+
+// checkRateLimited is used for checking if NGC throttled a request, 429 responses are throttling with or without a
+// Retry-After header, 503 responses only with one. Requests are held off for the Retry-After delay, or
+// DefaultRetryAfter if missing, and ErrRateLimited is returned. Returns nil for other responses.
+func (c *Client) checkRateLimited(resp *http.Response) error {
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+		return nil
+	}
+
+	retryAfter, found := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+	if !found {
+		if resp.StatusCode == http.StatusServiceUnavailable {
+			return nil
+		}
+		retryAfter = DefaultRetryAfter
+	}
+
+	c.holdOff(retryAfter)
+	return fmt.Errorf("%w: status %s, retry after %s", ErrRateLimited, resp.Status, retryAfter)
+}
+
+// parseRetryAfter is used for parsing a Retry-After header, either delay seconds or an HTTP date, the delay is capped
+// at maxRetryAfter. Returns false for missing or malformed headers.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	var delay time.Duration
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		delay = maxRetryAfter
+		if seconds < int64(maxRetryAfter/time.Second) {
+			delay = time.Duration(seconds) * time.Second
+		}
+	} else if date, err := http.ParseTime(value); err == nil {
+		delay = date.Sub(now)
+	} else {
+		return 0, false
+	}
+
+	if delay < 0 {
+		return 0, true
+	}
+	if delay > maxRetryAfter {
+		return maxRetryAfter, true
+	}
+	return delay, true
+}
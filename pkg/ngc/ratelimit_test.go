@@ -0,0 +1,96 @@
+// Copyright (c) 2024 Red Hat, Inc.
+
+package ngc
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"time"
+)
+
+var _ = Describe("Rate limiting", func() {
+	var server *httptest.Server
+	var status int
+	var retryAfter string
+	var requests atomic.Int32
+
+	BeforeEach(func() {
+		status = http.StatusOK
+		retryAfter = ""
+		requests.Store(0)
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests.Add(1)
+			if retryAfter != "" {
+				w.Header().Set("Retry-After", retryAfter)
+			}
+			w.WriteHeader(status)
+			_, _ = w.Write([]byte(`{"token":"my-token","expires_in":300}`))
+		}))
+		DeferCleanup(server.Close)
+	})
+
+	newClient := func(opts ClientOptions) *Client {
+		opts.AuthUrl = server.URL
+		client, err := NewClient(opts)
+		Expect(err).NotTo(HaveOccurred())
+		return client
+	}
+
+	DescribeTable("holding off requests throttled by NGC",
+		func(ctx SpecContext, responseStatus int, responseRetryAfter string, expected time.Duration) {
+			status = responseStatus
+			retryAfter = responseRetryAfter
+			client := newClient(ClientOptions{})
+
+			Expect(client.ValidateApiKey(ctx, "my-api-key")).To(MatchError(ErrRateLimited))
+			Expect(client.RetryAfter()).To(BeNumerically("~", expected, time.Second))
+
+			By("failing fast while held off")
+			status = http.StatusOK
+			Expect(client.ValidateApiKey(ctx, "my-api-key")).To(MatchError(ErrRateLimited))
+			Expect(requests.Load()).To(Equal(int32(1)))
+		},
+		Entry("too many requests with delay seconds", http.StatusTooManyRequests, "120", 2*time.Minute),
+		Entry("too many requests with no delay", http.StatusTooManyRequests, "", DefaultRetryAfter),
+		Entry("service unavailable with delay seconds", http.StatusServiceUnavailable, "30", 30*time.Second),
+		Entry("capped delay", http.StatusTooManyRequests, "86400", maxRetryAfter),
+	)
+
+	It("should fail fast when the client-side rate limit is exceeded", func(ctx SpecContext) {
+		client := newClient(ClientOptions{RateLimit: 0.01, RateBurst: 1})
+
+		Expect(client.ValidateApiKey(ctx, "my-api-key")).To(Succeed())
+		Expect(client.ValidateApiKey(ctx, "my-api-key")).To(MatchError(ErrRateLimited))
+		Expect(client.RetryAfter()).To(BeNumerically(">", maxRateLimitWait))
+		Expect(requests.Load()).To(Equal(int32(1)))
+	})
+
+	It("should wait for the client-side rate limiter within the limit", func(ctx SpecContext) {
+		client := newClient(ClientOptions{RateLimit: 20, RateBurst: 1})
+
+		for i := 0; i < 3; i++ {
+			Expect(client.ValidateApiKey(ctx, "my-api-key")).To(Succeed())
+		}
+		Expect(client.RetryAfter()).To(BeZero())
+		Expect(requests.Load()).To(Equal(int32(3)))
+	})
+
+	DescribeTable("parsing the Retry-After header",
+		func(value string, expected time.Duration, expectedFound bool) {
+			now := time.Date(2024, 6, 1, 10, 30, 0, 0, time.UTC)
+			delay, found := parseRetryAfter(value, now)
+			Expect(found).To(Equal(expectedFound))
+			Expect(delay).To(Equal(expected))
+		},
+		Entry("delay seconds", "90", 90*time.Second, true),
+		Entry("HTTP date", "Sat, 01 Jun 2024 10:32:00 GMT", 2*time.Minute, true),
+		Entry("HTTP date in the past", "Sat, 01 Jun 2024 10:00:00 GMT", time.Duration(0), true),
+		Entry("missing", "", time.Duration(0), false),
+		Entry("negative", "-1", time.Duration(0), false),
+		Entry("malformed", "soon", time.Duration(0), false),
+	)
+})
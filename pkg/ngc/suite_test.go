@@ -0,0 +1,14 @@
+// Copyright (c) 2024 Red Hat, Inc.
+
+package ngc
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"testing"
+)
+
+func TestNgc(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "NGC Client Tests")
+}
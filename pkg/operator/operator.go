@@ -3,21 +3,27 @@
 package operator
 
 import (
-	"github.com/opendatahub-io/odh-nim-operator/api/v1alpha1"
+	"github.com/opendatahub-io/odh-nim-operator/api/v1beta1"
 	"github.com/opendatahub-io/odh-nim-operator/pkg/controllers"
+	"github.com/opendatahub-io/odh-nim-operator/pkg/ngc"
 	"github.com/opendatahub-io/odh-nim-operator/pkg/utils"
 	"github.com/opendatahub-io/odh-nim-operator/pkg/webhooks"
 	"github.com/spf13/cobra"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"os"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"strings"
 )
 
+// serviceAccountNamespaceFile is the file holding the operator namespace, mounted in the operator pod
+const serviceAccountNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
 // OdhNimOperator is the receiver for running the operator and binding the options
 type OdhNimOperator struct {
 	Options OdhNimOperatorOptions
@@ -25,11 +31,13 @@ type OdhNimOperator struct {
 
 // OdhNimOperatorOptions is used for encapsulating the operator options
 type OdhNimOperatorOptions struct {
-	MetricAddr     string
-	LeaderElection bool
-	ProbeAddr      string
-	Debug          bool
-	EnableWebhooks bool
+	MetricAddr       string
+	LeaderElection   bool
+	ProbeAddr        string
+	Debug            bool
+	EnableWebhooks   bool
+	OperatorUsername string
+	NgcOptions       ngc.ClientOptions
 	controllers.ControllerOptions
 }
 
@@ -63,7 +71,7 @@ func (o *OdhNimOperator) Run(cmd *cobra.Command, args []string) error {
 		HealthProbeBindAddress: o.Options.ProbeAddr,
 		NewCache: cache.BuilderWithOptions(cache.Options{
 			SelectorsByObject: cache.SelectorsByObject{
-				&v1alpha1.OdhNimApp{}: {Label: labels.Everything()},
+				&v1beta1.OdhNimApp{}: {Label: labels.Everything()},
 			},
 		}),
 	})
@@ -72,8 +80,26 @@ func (o *OdhNimOperator) Run(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	// create the ngc client
+	ngcClient, err := ngc.NewClient(o.Options.NgcOptions)
+	if err != nil {
+		logger.Error(err, "failed creating ngc client")
+		return err
+	}
+
+	// the PrometheusRule defaults to the operator namespace, only required if the PrometheusRule is enabled
+	if o.Options.PrometheusRuleNamespace == "" {
+		namespace, err := os.ReadFile(serviceAccountNamespaceFile)
+		if err != nil && o.Options.EnablePrometheusRules {
+			logger.Error(err, "failed reading the operator namespace, set the PrometheusRule namespace")
+			return err
+		}
+		o.Options.PrometheusRuleNamespace = strings.TrimSpace(string(namespace))
+	}
+
 	// setup controllers
 	o.Options.ControllerOptions.Manager = mgr
+	o.Options.ControllerOptions.NgcClient = ngcClient
 	if err = controllers.SetupControllers(o.Options.ControllerOptions); err != nil {
 		logger.Error(err, "failed setting up the controllers")
 		return err
@@ -81,7 +107,7 @@ func (o *OdhNimOperator) Run(cmd *cobra.Command, args []string) error {
 
 	// setup webhooks
 	if o.Options.EnableWebhooks {
-		wopts := webhooks.WebhookOptions{Manager: mgr}
+		wopts := webhooks.WebhookOptions{Manager: mgr, OperatorUsername: o.Options.OperatorUsername}
 		if err = webhooks.SetupWebhooks(wopts); err != nil {
 			logger.Error(err, "failed setting up the webhooks")
 			return err
@@ -4,6 +4,9 @@ package utils
 
 import (
 	"github.com/opendatahub-io/odh-nim-operator/api/v1alpha1"
+	"github.com/opendatahub-io/odh-nim-operator/api/v1beta1"
+	templatev1 "github.com/openshift/api/template/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 )
@@ -11,8 +14,11 @@ import (
 // InstallTypes is used for installing our required types with a given scheme.
 func InstallTypes(scheme *runtime.Scheme) error {
 	installs := []func(*runtime.Scheme) error{
-		v1alpha1.Install,   // our own api
-		corev1.AddToScheme, // ConfigMaps and Secrets
+		v1beta1.Install,     // our own api
+		v1alpha1.Install,    // our own deprecated api, converted to v1beta1
+		corev1.AddToScheme,  // ConfigMaps, Secrets, and PVCs
+		batchv1.AddToScheme, // model cache warm-up Jobs
+		templatev1.Install,  // OpenShift Templates
 	}
 
 	for _, install := range installs {
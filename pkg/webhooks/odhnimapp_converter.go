@@ -0,0 +1,25 @@
+// Copyright (c) 2024 Red Hat, Inc.
+
+package webhooks
+
+import (
+	"github.com/opendatahub-io/odh-nim-operator/api/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// OdhNimAppConverter is used for converting OdhNimApp between the served versions. v1beta1 is the hub, the other
+// versions are spokes converting to and from it (check the conversion.go files in the api packages).
+type OdhNimAppConverter struct{}
+
+// init is used for registering the converter for loading
+func init() {
+	webhooksSetups = append(webhooksSetups, func(opts WebhookOptions) error {
+		return (&OdhNimAppConverter{}).SetupWithManager(opts.Manager)
+	})
+}
+
+// SetupWithManager is used for setting up the conversion webhook with a manager (check the init function), the
+// conversion is served on /convert for all convertible types in the manager's scheme
+func (w *OdhNimAppConverter) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&v1beta1.OdhNimApp{}).Complete()
+}
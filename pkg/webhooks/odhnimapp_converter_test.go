@@ -0,0 +1,97 @@
+// Copyright (c) 2024 Red Hat, Inc.
+
+package webhooks
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/opendatahub-io/odh-nim-operator/api/v1alpha1"
+	"github.com/opendatahub-io/odh-nim-operator/api/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("OdhNimApp conversion", func() {
+	var spoke *v1alpha1.OdhNimApp
+
+	BeforeEach(func() {
+		spoke = &v1alpha1.OdhNimApp{
+			ObjectMeta: metav1.ObjectMeta{Name: "odh-nim-app", Namespace: "my-namespace"},
+			Spec: v1alpha1.OdhNimAppSpec{
+				ApiKey: v1alpha1.OdhNimAppSpecApiKey{
+					Validate:  true,
+					SecretRef: &corev1.ObjectReference{Name: "odh-nim-app-api-key", Namespace: "my-namespace"},
+				},
+				Content: v1alpha1.OdhNimAppSpecContent{
+					ConfigMapRef: &corev1.ObjectReference{Name: "odh-nim-app-content", Namespace: "my-namespace"},
+				},
+				TemplateRef:    &corev1.ObjectReference{Name: "nvidia-nim-serving-template", Namespace: "my-namespace"},
+				TeardownPolicy: v1alpha1.TeardownPolicyDelete,
+				Schedule:       v1alpha1.OdhNimAppSpecSchedule{Cron: "0 3 * * *"},
+			},
+			Status: v1alpha1.OdhNimAppStatus{
+				Conditions: []metav1.Condition{
+					{Type: "ApiKeyValidated", Status: metav1.ConditionTrue, Reason: "ApiKeyValidatedSuccessfully"},
+				},
+				ObservedValidateRequestedAt: "2024-09-26T00:00:00Z",
+			},
+		}
+	})
+
+	It("should move the references to the hub status", func() {
+		hub := &v1beta1.OdhNimApp{}
+		Expect(spoke.ConvertTo(hub)).To(Succeed())
+
+		Expect(hub.Spec.ApiKey.Validate).To(BeTrue())
+		Expect(hub.Spec.ApiKey.SecretRef.Name).To(Equal("odh-nim-app-api-key"))
+		Expect(hub.Spec.TeardownPolicy).To(Equal(v1beta1.TeardownPolicyDelete))
+		Expect(hub.Spec.Schedule.Cron).To(Equal("0 3 * * *"))
+		Expect(hub.Status.ConfigMapRef.Name).To(Equal("odh-nim-app-content"))
+		Expect(hub.Status.TemplateRef.Name).To(Equal("nvidia-nim-serving-template"))
+		Expect(hub.Status.Conditions).To(Equal(spoke.Status.Conditions))
+		Expect(hub.Status.ObservedValidateRequestedAt).To(Equal("2024-09-26T00:00:00Z"))
+	})
+
+	It("should move the references back to the spoke spec", func() {
+		hub := &v1beta1.OdhNimApp{}
+		Expect(spoke.ConvertTo(hub)).To(Succeed())
+		hub.Status.ObservedGeneration = 3
+
+		converted := &v1alpha1.OdhNimApp{}
+		Expect(converted.ConvertFrom(hub)).To(Succeed())
+		Expect(converted.Spec).To(Equal(spoke.Spec))
+		Expect(converted.Status).To(Equal(spoke.Status))
+
+		By("preserving the observed generation over a round trip")
+		roundTrip := &v1beta1.OdhNimApp{}
+		Expect(converted.ConvertTo(roundTrip)).To(Succeed())
+		Expect(roundTrip).To(Equal(hub))
+	})
+
+	It("should preserve the fields added to the hub over a round trip", func() {
+		hub := &v1beta1.OdhNimApp{}
+		Expect(spoke.ConvertTo(hub)).To(Succeed())
+		hub.Spec.Propagation = v1beta1.OdhNimAppSpecPropagation{NamespaceSelector: &metav1.LabelSelector{
+			MatchLabels: map[string]string{"modelmesh-enabled": "true"},
+		}, Namespaces: []string{"my-models"}}
+		hub.Spec.Warmup.Models = []string{"llama3-8b-instruct"}
+		hub.Spec.Offline.CatalogRef = &corev1.ObjectReference{Name: "nim-catalog-bundle"}
+		hub.Spec.Mirrors = map[string]string{"nvcr.io/nim": "registry.internal/nim"}
+		hub.Status.ApiKeyHash = "0123456789abcdef"
+		hub.Status.ConsecutiveContentFailures = 2
+		hub.Status.LastCatalogDiff = &v1beta1.OdhNimAppStatusCatalogDiff{
+			Summary:     "1 models added, 0 removed, 0 updated",
+			AddedModels: []string{"llama3-8b-instruct"},
+		}
+
+		converted := &v1alpha1.OdhNimApp{}
+		Expect(converted.ConvertFrom(hub)).To(Succeed())
+		Expect(converted.Spec).To(Equal(spoke.Spec))
+		Expect(converted.Status).To(Equal(spoke.Status))
+		Expect(converted.Annotations).To(HaveKey("nim.opendatahub.io/v1beta1-fields"))
+
+		roundTrip := &v1beta1.OdhNimApp{}
+		Expect(converted.ConvertTo(roundTrip)).To(Succeed())
+		Expect(roundTrip).To(Equal(hub))
+	})
+})
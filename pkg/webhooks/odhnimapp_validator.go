@@ -5,49 +5,152 @@ package webhooks
 import (
 	"context"
 	"fmt"
-	"github.com/opendatahub-io/odh-nim-operator/api/v1alpha1"
+	"github.com/opendatahub-io/odh-nim-operator/api/v1beta1"
+	"github.com/robfig/cron/v3"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	"strings"
 )
 
-// +kubebuilder:webhook:verbs=create;update;delete,path=/validate-nim-opendatahub-io-v1alpha1-odhnimapp,mutating=false,failurePolicy=fail,groups=nim.opendatahub.io,resources=odhnimapps,versions=v1alpha1,name=validate.nim.opendatahub.io.v1alpha1.odhnimapp,sideEffects=None,admissionReviewVersions=v1
+// +kubebuilder:webhook:verbs=create;update;delete,path=/validate-nim-opendatahub-io-v1beta1-odhnimapp,mutating=false,failurePolicy=fail,groups=nim.opendatahub.io,resources=odhnimapps,versions=v1beta1,name=validate.nim.opendatahub.io.v1beta1.odhnimapp,sideEffects=None,admissionReviewVersions=v1
 
+// systemUsernames are the cluster identities deleting OdhNimApp on the cluster behalf, i.e. when deleting a namespace
+var systemUsernames = []string{
+	"system:serviceaccount:kube-system:namespace-controller",
+	"system:serviceaccount:kube-system:generic-garbage-collector",
+}
+
+// OdhNimAppValidator is used for validating OdhNimApp admission requests. Only the ODH NIM Operator, identified by
+// OperatorUsername, is allowed to Create or Delete OdhNimApp, other identities can only trigger validation or content
+// fetch.
 type OdhNimAppValidator struct {
 	client.Client
+	OperatorUsername string
+}
+
+// init is used for registering the validator for loading
+func init() {
+	webhooksSetups = append(webhooksSetups, func(opts WebhookOptions) error {
+		if opts.OperatorUsername == "" {
+			return fmt.Errorf("the operator username is required for validating OdhNimApp")
+		}
+		return (&OdhNimAppValidator{opts.Manager.GetClient(), opts.OperatorUsername}).SetupWithManager(opts.Manager)
+	})
 }
 
 // SetupWithManager is used for setting up the webhook with a manager (check the init function)
 func (w *OdhNimAppValidator) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewWebhookManagedBy(mgr).For(&v1alpha1.OdhNimApp{}).WithValidator(w).Complete()
+	return ctrl.NewWebhookManagedBy(mgr).For(&v1beta1.OdhNimApp{}).WithValidator(w).Complete()
 }
 
+// ValidateCreate is used for allowing only the ODH NIM Operator to Create OdhNimApp, one per namespace
 func (w *OdhNimAppValidator) ValidateCreate(ctx context.Context, obj runtime.Object) error {
-	// TODO Only the ODH NIM Operator is allowed to Create or Delete OdhNimApp
+	app := obj.(*v1beta1.OdhNimApp)
+	if err := w.verifyOperator(ctx, app, "create"); err != nil {
+		return err
+	}
+	if err := verifySchedule(app); err != nil {
+		return err
+	}
+	if err := verifyPropagation(app); err != nil {
+		return err
+	}
+	if err := verifyMirrors(app); err != nil {
+		return err
+	}
+	if err := verifyOffline(app); err != nil {
+		return err
+	}
 	return w.verifyOnlyOneInNamespace(ctx, obj)
 }
 
+// ValidateUpdate is used for allowing users to only Update the OdhNimApp.Spec{.ApiKey.Validate | .Content.Update} keys
+// to true, triggering validation or content fetch, the teardown policy, the schedule, the storage, the warm-up, and the
+// offline mode. Any other spec keys, the propagation copying the API key to other namespaces and the mirrors
+// redirecting the NIM images included, can only be updated by the ODH NIM Operator, OdhNimApps not setting the
+// propagation use the operator --propagation-namespace-selector. Metadata and status are not validated.
 func (w *OdhNimAppValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) error {
-	// TODO Users can only Update the OdhNimApp.Spec{.ApiKey.Validate | .Content.Update } keys triggering validation or
-	// TODO content fetch, any other spec keys can only be updated by the ODH NIM Operator
+	oldApp := oldObj.(*v1beta1.OdhNimApp)
+	newApp := newObj.(*v1beta1.OdhNimApp)
+
+	if err := verifySchedule(newApp); err != nil {
+		return err
+	}
+	if err := verifyPropagation(newApp); err != nil {
+		return err
+	}
+	if err := verifyMirrors(newApp); err != nil {
+		return err
+	}
+	if err := verifyOffline(newApp); err != nil {
+		return err
+	}
+	if err := verifyStorageExpansion(oldApp, newApp); err != nil {
+		return err
+	}
+
+	username, err := w.getUsername(ctx)
+	if err != nil {
+		return err
+	}
+	if username == w.OperatorUsername {
+		return nil
+	}
+
+	// apply the modifications allowed for users on the old spec and compare with the new one
+	allowedSpec := oldApp.Spec.DeepCopy()
+	if newApp.Spec.ApiKey.Validate {
+		allowedSpec.ApiKey.Validate = true
+	}
+	if newApp.Spec.Content.Update {
+		allowedSpec.Content.Update = true
+	}
+	allowedSpec.TeardownPolicy = newApp.Spec.TeardownPolicy
+	allowedSpec.Schedule = newApp.Spec.Schedule
+	allowedSpec.Storage = newApp.Spec.Storage
+	allowedSpec.Warmup = newApp.Spec.Warmup
+	allowedSpec.Offline = newApp.Spec.Offline
+
+	if !equality.Semantic.DeepEqual(*allowedSpec, newApp.Spec) {
+		logger := log.FromContext(ctx).WithName("odhnimapp-validator-webhook")
+		logger.V(1).Info(fmt.Sprintf("denied spec modification for %s", username))
+		return forbidden(newApp, fmt.Errorf("%s can only set spec.apiKey.validate and spec.content.update to true, "+
+			"and modify spec.teardownPolicy, spec.schedule, spec.storage, spec.warmup, and spec.offline",
+			username))
+	}
 	return nil
 }
 
+// ValidateDelete is used for allowing only the ODH NIM Operator to Delete OdhNimApp
 func (w *OdhNimAppValidator) ValidateDelete(ctx context.Context, obj runtime.Object) error {
-	// TODO Only the ODH NIM Operator is allowed to Create or Delete OdhNimApp
-	return nil
+	app := obj.(*v1beta1.OdhNimApp)
+	username, err := w.getUsername(ctx)
+	if err != nil {
+		return err
+	}
+	for _, systemUsername := range systemUsernames {
+		if username == systemUsername {
+			return nil
+		}
+	}
+	return w.verifyOperator(ctx, app, "delete")
 }
 
 func (w *OdhNimAppValidator) verifyOnlyOneInNamespace(ctx context.Context, obj runtime.Object) error {
 	logger := log.FromContext(ctx).WithName("odhnimapp-validator-webhook")
 
-	ns := obj.(*v1alpha1.OdhNimApp).Namespace
+	ns := obj.(*v1beta1.OdhNimApp).Namespace
 
 	ocfgs := &metav1.PartialObjectMetadataList{}
-	ocfgs.SetGroupVersionKind(v1alpha1.GroupVersion.WithKind("OdhNimAppList"))
+	ocfgs.SetGroupVersionKind(v1beta1.GroupVersion.WithKind("OdhNimAppList"))
 	if err := w.Client.List(ctx, ocfgs, client.InNamespace(ns)); err != nil && !errors.IsNotFound(err) {
 		return err
 	}
@@ -60,3 +163,115 @@ func (w *OdhNimAppValidator) verifyOnlyOneInNamespace(ctx context.Context, obj r
 	logger.V(1).Info(fmt.Sprintf("no OdhNimApp instances found in %s", ns))
 	return nil
 }
+
+// verifyOperator is used for verifying the admission request was sent by the ODH NIM Operator
+func (w *OdhNimAppValidator) verifyOperator(ctx context.Context, app *v1beta1.OdhNimApp, verb string) error {
+	username, err := w.getUsername(ctx)
+	if err != nil {
+		return err
+	}
+	if username != w.OperatorUsername {
+		logger := log.FromContext(ctx).WithName("odhnimapp-validator-webhook")
+		logger.V(1).Info(fmt.Sprintf("denied %s for %s", verb, username))
+		return forbidden(app, fmt.Errorf("only the ODH NIM Operator is allowed to %s OdhNimApp", verb))
+	}
+	return nil
+}
+
+// verifySchedule is used for verifying the OdhNimApp schedule cron expression can be parsed
+func verifySchedule(app *v1beta1.OdhNimApp) error {
+	if app.Spec.Schedule.Cron == "" {
+		return nil
+	}
+	if _, err := cron.ParseStandard(app.Spec.Schedule.Cron); err != nil {
+		return errors.NewInvalid(v1beta1.GroupVersion.WithKind("OdhNimApp").GroupKind(), app.Name, field.ErrorList{
+			field.Invalid(field.NewPath("spec", "schedule", "cron"), app.Spec.Schedule.Cron, err.Error()),
+		})
+	}
+	return nil
+}
+
+// verifyPropagation is used for verifying the OdhNimApp propagation namespace selector can be converted to a selector,
+// and the namespaces are namespace names. An empty selector would select every namespace, it is only allowed with
+// namespaces listed.
+func verifyPropagation(app *v1beta1.OdhNimApp) error {
+	propagation := app.Spec.Propagation
+	path := field.NewPath("spec", "propagation")
+
+	var errs field.ErrorList
+	if selector := propagation.NamespaceSelector; selector != nil {
+		if _, err := metav1.LabelSelectorAsSelector(selector); err != nil {
+			errs = append(errs, field.Invalid(path.Child("namespaceSelector"), selector, err.Error()))
+		} else if len(selector.MatchLabels) == 0 && len(selector.MatchExpressions) == 0 &&
+			len(propagation.Namespaces) == 0 {
+			errs = append(errs, field.Invalid(path.Child("namespaceSelector"), selector,
+				"an empty selector selects every namespace, set labels or expressions, or list the namespaces"))
+		}
+	}
+	for i, namespace := range propagation.Namespaces {
+		for _, msg := range validation.IsDNS1123Label(namespace) {
+			errs = append(errs, field.Invalid(path.Child("namespaces").Index(i), namespace, msg))
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errors.NewInvalid(v1beta1.GroupVersion.WithKind("OdhNimApp").GroupKind(), app.Name, errs)
+}
+
+// verifyMirrors is used for verifying the OdhNimApp mirrors map image repositories, without a scheme, to mirrors
+func verifyMirrors(app *v1beta1.OdhNimApp) error {
+	var errs field.ErrorList
+	for source, mirror := range app.Spec.Mirrors {
+		path := field.NewPath("spec", "mirrors").Key(source)
+		switch {
+		case source == "" || mirror == "":
+			errs = append(errs, field.Invalid(path, mirror, "source and mirror are required"))
+		case strings.Contains(source, "://") || strings.Contains(mirror, "://"):
+			errs = append(errs, field.Invalid(path, mirror, "source and mirror are image repositories, not urls"))
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errors.NewInvalid(v1beta1.GroupVersion.WithKind("OdhNimApp").GroupKind(), app.Name, errs)
+}
+
+// verifyOffline is used for verifying the OdhNimApp offline catalog ConfigMap is in the OdhNimApp namespace, so users
+// can't read the catalog of other namespaces
+func verifyOffline(app *v1beta1.OdhNimApp) error {
+	ref := app.Spec.Offline.CatalogRef
+	if ref == nil || ref.Namespace == "" || ref.Namespace == app.Namespace {
+		return nil
+	}
+	return errors.NewInvalid(v1beta1.GroupVersion.WithKind("OdhNimApp").GroupKind(), app.Name, field.ErrorList{
+		field.Invalid(field.NewPath("spec", "offline", "catalogRef", "namespace"), ref.Namespace,
+			"must be the OdhNimApp namespace"),
+	})
+}
+
+// verifyStorageExpansion is used for verifying the OdhNimApp storage size is not reduced, PVCs can only be expanded
+func verifyStorageExpansion(oldApp, newApp *v1beta1.OdhNimApp) error {
+	oldSize, newSize := oldApp.Spec.Storage.Size, newApp.Spec.Storage.Size
+	if oldSize == nil || newSize == nil || newSize.Cmp(*oldSize) >= 0 {
+		return nil
+	}
+	return errors.NewInvalid(v1beta1.GroupVersion.WithKind("OdhNimApp").GroupKind(), newApp.Name, field.ErrorList{
+		field.Invalid(field.NewPath("spec", "storage", "size"), newSize.String(),
+			fmt.Sprintf("can only be expanded from %s", oldSize.String())),
+	})
+}
+
+// getUsername is used for getting the username of the identity sending the admission request
+func (w *OdhNimAppValidator) getUsername(ctx context.Context) (string, error) {
+	req, err := admission.RequestFromContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	return req.UserInfo.Username, nil
+}
+
+// forbidden is used for creating a forbidden error for an OdhNimApp
+func forbidden(app *v1beta1.OdhNimApp, err error) error {
+	return errors.NewForbidden(v1beta1.GroupVersion.WithResource("odhnimapps").GroupResource(), app.Name, err)
+}
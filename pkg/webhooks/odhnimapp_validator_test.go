@@ -0,0 +1,223 @@
+// Copyright (c) 2024 Red Hat, Inc.
+
+package webhooks
+
+import (
+	"context"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/opendatahub-io/odh-nim-operator/api/v1beta1"
+	"github.com/opendatahub-io/odh-nim-operator/pkg/utils"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const (
+	testOperatorUsername = "system:serviceaccount:odh-nim-operator-system:odh-nim-operator-sa"
+	testUsername         = "developer"
+)
+
+var _ = Describe("OdhNimAppValidator", func() {
+	var validator *OdhNimAppValidator
+	var app *v1beta1.OdhNimApp
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(utils.InstallTypes(scheme)).To(Succeed())
+		validator = &OdhNimAppValidator{fake.NewClientBuilder().WithScheme(scheme).Build(), testOperatorUsername}
+
+		app = &v1beta1.OdhNimApp{
+			ObjectMeta: metav1.ObjectMeta{Name: "odh-nim-app", Namespace: "my-namespace"},
+			Spec: v1beta1.OdhNimAppSpec{
+				ApiKey: v1beta1.OdhNimAppSpecApiKey{
+					SecretRef: &corev1.ObjectReference{Name: "odh-nim-app-api-key", Namespace: "my-namespace"},
+				},
+			},
+		}
+	})
+
+	Context("creating and deleting", func() {
+		It("should allow the operator", func() {
+			ctx := newTestContext(testOperatorUsername)
+			Expect(validator.ValidateCreate(ctx, app)).To(Succeed())
+			Expect(validator.ValidateDelete(ctx, app)).To(Succeed())
+		})
+
+		It("should deny other users", func() {
+			ctx := newTestContext(testUsername)
+			Expect(k8serrors.IsForbidden(validator.ValidateCreate(ctx, app))).To(BeTrue())
+			Expect(k8serrors.IsForbidden(validator.ValidateDelete(ctx, app))).To(BeTrue())
+		})
+
+		It("should allow deleting the namespace", func() {
+			ctx := newTestContext("system:serviceaccount:kube-system:namespace-controller")
+			Expect(validator.ValidateDelete(ctx, app)).To(Succeed())
+		})
+
+		It("should deny a second instance in the namespace", func() {
+			Expect(validator.Client.Create(context.Background(), app.DeepCopy())).To(Succeed())
+
+			other := app.DeepCopy()
+			other.Name = "other-odh-nim-app"
+			other.ResourceVersion = ""
+			Expect(validator.ValidateCreate(newTestContext(testOperatorUsername), other)).NotTo(Succeed())
+		})
+	})
+
+	DescribeTable("updating the spec",
+		func(username string, modify func(spec *v1beta1.OdhNimAppSpec), allowed bool) {
+			newApp := app.DeepCopy()
+			modify(&newApp.Spec)
+
+			err := validator.ValidateUpdate(newTestContext(username), app, newApp)
+			if allowed {
+				Expect(err).NotTo(HaveOccurred())
+			} else {
+				Expect(k8serrors.IsForbidden(err)).To(BeTrue())
+			}
+		},
+		Entry("users can request validation", testUsername,
+			func(spec *v1beta1.OdhNimAppSpec) { spec.ApiKey.Validate = true }, true),
+		Entry("users can request content update", testUsername,
+			func(spec *v1beta1.OdhNimAppSpec) { spec.Content.Update = true }, true),
+		Entry("users can modify the teardown policy", testUsername,
+			func(spec *v1beta1.OdhNimAppSpec) { spec.TeardownPolicy = v1beta1.TeardownPolicyDelete }, true),
+		Entry("users can modify the schedule", testUsername,
+			func(spec *v1beta1.OdhNimAppSpec) { spec.Schedule.Cron = "0 3 * * *" }, true),
+		Entry("users can not modify the propagation", testUsername,
+			func(spec *v1beta1.OdhNimAppSpec) {
+				spec.Propagation.NamespaceSelector = &metav1.LabelSelector{
+					MatchLabels: map[string]string{"opendatahub.io/dashboard": "true"},
+				}
+			}, false),
+		Entry("users can not list propagation namespaces", testUsername,
+			func(spec *v1beta1.OdhNimAppSpec) { spec.Propagation.Namespaces = []string{"other-namespace"} }, false),
+		Entry("users can modify the storage", testUsername,
+			func(spec *v1beta1.OdhNimAppSpec) {
+				size := resource.MustParse("100Gi")
+				spec.Storage.Size = &size
+				spec.Storage.AccessMode = corev1.ReadWriteMany
+			}, true),
+		Entry("users can modify the warm-up", testUsername,
+			func(spec *v1beta1.OdhNimAppSpec) { spec.Warmup.Models = []string{"llama3-8b-instruct"} }, true),
+		Entry("users can modify the offline mode", testUsername,
+			func(spec *v1beta1.OdhNimAppSpec) {
+				spec.Offline.CatalogRef = &corev1.ObjectReference{Name: "nim-catalog-bundle"}
+			}, true),
+		Entry("users can not modify the mirrors", testUsername,
+			func(spec *v1beta1.OdhNimAppSpec) {
+				spec.Mirrors = map[string]string{"nvcr.io/nim": "registry.internal/nim"}
+			}, false),
+		Entry("users can not modify the secret reference", testUsername,
+			func(spec *v1beta1.OdhNimAppSpec) { spec.ApiKey.SecretRef.Name = "other-secret" }, false),
+		Entry("users can not remove the secret reference", testUsername,
+			func(spec *v1beta1.OdhNimAppSpec) { spec.ApiKey.SecretRef = nil }, false),
+		Entry("the operator can modify any key", testOperatorUsername,
+			func(spec *v1beta1.OdhNimAppSpec) { spec.ApiKey.SecretRef.Name = "other-secret" }, true),
+	)
+
+	It("should deny invalid cron expressions", func() {
+		newApp := app.DeepCopy()
+		newApp.Spec.Schedule.Cron = "every day"
+		err := validator.ValidateUpdate(newTestContext(testOperatorUsername), app, newApp)
+		Expect(k8serrors.IsInvalid(err)).To(BeTrue())
+	})
+
+	DescribeTable("verifying the mirrors",
+		func(source, mirror string, valid bool) {
+			newApp := app.DeepCopy()
+			newApp.Spec.Mirrors = map[string]string{source: mirror}
+			err := validator.ValidateUpdate(newTestContext(testOperatorUsername), app, newApp)
+			if valid {
+				Expect(err).NotTo(HaveOccurred())
+			} else {
+				Expect(k8serrors.IsInvalid(err)).To(BeTrue())
+			}
+		},
+		Entry("image repositories are valid", "nvcr.io/nim", "registry.internal/nim", true),
+		Entry("empty mirrors are invalid", "nvcr.io/nim", "", false),
+		Entry("urls are invalid", "nvcr.io/nim", "https://registry.internal/nim", false),
+	)
+
+	DescribeTable("verifying the offline catalog",
+		func(namespace string, valid bool) {
+			newApp := app.DeepCopy()
+			newApp.Spec.Offline.CatalogRef = &corev1.ObjectReference{Name: "nim-catalog-bundle", Namespace: namespace}
+			err := validator.ValidateUpdate(newTestContext(testUsername), app, newApp)
+			if valid {
+				Expect(err).NotTo(HaveOccurred())
+			} else {
+				Expect(k8serrors.IsInvalid(err)).To(BeTrue())
+			}
+		},
+		Entry("the OdhNimApp namespace is valid", "", true),
+		Entry("other namespaces are invalid", "other-namespace", false),
+	)
+
+	DescribeTable("verifying the propagation",
+		func(propagation v1beta1.OdhNimAppSpecPropagation, valid bool) {
+			newApp := app.DeepCopy()
+			newApp.Spec.Propagation = propagation
+			err := validator.ValidateUpdate(newTestContext(testOperatorUsername), app, newApp)
+			if valid {
+				Expect(err).NotTo(HaveOccurred())
+			} else {
+				Expect(k8serrors.IsInvalid(err)).To(BeTrue())
+			}
+		},
+		Entry("labeled selectors are valid", v1beta1.OdhNimAppSpecPropagation{
+			NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"modelmesh-enabled": "true"}},
+		}, true),
+		Entry("listed namespaces are valid", v1beta1.OdhNimAppSpecPropagation{
+			Namespaces: []string{"my-models", "my-other-models"},
+		}, true),
+		Entry("invalid selectors are invalid", v1beta1.OdhNimAppSpecPropagation{
+			NamespaceSelector: &metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "modelmesh-enabled", Operator: "Matches"}},
+			},
+		}, false),
+		Entry("empty selectors are invalid", v1beta1.OdhNimAppSpecPropagation{
+			NamespaceSelector: &metav1.LabelSelector{},
+		}, false),
+		Entry("invalid namespace names are invalid", v1beta1.OdhNimAppSpecPropagation{
+			Namespaces: []string{"My_Models"},
+		}, false),
+	)
+
+	It("should deny reducing the storage size", func() {
+		size := resource.MustParse("100Gi")
+		app.Spec.Storage.Size = &size
+
+		newApp := app.DeepCopy()
+		smaller := resource.MustParse("10Gi")
+		newApp.Spec.Storage.Size = &smaller
+		err := validator.ValidateUpdate(newTestContext(testOperatorUsername), app, newApp)
+		Expect(k8serrors.IsInvalid(err)).To(BeTrue())
+	})
+
+	It("should deny users from cancelling requests", func() {
+		app.Spec.ApiKey.Validate = true
+		app.Spec.Content.Update = true
+
+		newApp := app.DeepCopy()
+		newApp.Spec.ApiKey.Validate = false
+		Expect(k8serrors.IsForbidden(validator.ValidateUpdate(newTestContext(testUsername), app, newApp))).To(BeTrue())
+
+		newApp = app.DeepCopy()
+		newApp.Spec.Content.Update = false
+		Expect(k8serrors.IsForbidden(validator.ValidateUpdate(newTestContext(testUsername), app, newApp))).To(BeTrue())
+	})
+})
+
+// newTestContext is used for creating a context holding an admission request sent by username
+func newTestContext(username string) context.Context {
+	req := admission.Request{}
+	req.UserInfo = authenticationv1.UserInfo{Username: username}
+	return admission.NewContextWithRequest(context.Background(), req)
+}
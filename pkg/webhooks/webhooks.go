@@ -6,7 +6,8 @@ import ctrl "sigs.k8s.io/controller-runtime"
 
 // WebhookOptions is encapsulating the global options for use with all webhooks
 type WebhookOptions struct {
-	Manager ctrl.Manager
+	Manager          ctrl.Manager
+	OperatorUsername string
 }
 
 // webhooksSetups is used for registering webhooks for loading